@@ -0,0 +1,24 @@
+/* 
+export PASSWORD=secret
+*/
+
+locals {
+  yaml_1 = <<-EOT
+    root:
+      username: admin
+  EOT
+
+  yaml_2 = <<-EOT
+    root:
+      password: !env PASSWORD
+  EOT
+}
+
+ephemeral "utils_yaml_merge" "example" {
+  input = [local.yaml_1, local.yaml_2]
+}
+
+# the output is not persisted to the state or plan
+provider "example" {
+  config = ephemeral.utils_yaml_merge.example.output
+}
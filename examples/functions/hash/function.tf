@@ -0,0 +1,21 @@
+locals {
+  yaml_1 = <<-EOT
+    name: tenant1
+    vlans: [10, 20]
+  EOT
+
+  yaml_2 = <<-EOT
+    vlans:
+      - 10
+      - 20
+    name: 'tenant1'
+  EOT
+}
+
+output "equal" {
+  value = provider::utils::hash(local.yaml_1) == provider::utils::hash(local.yaml_2)
+}
+
+/* 
+equal = true
+*/
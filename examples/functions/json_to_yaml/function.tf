@@ -0,0 +1,18 @@
+locals {
+  json = <<-EOT
+    {"name": "tenant1", "version": 1.0, "vrfs": [{"name": "vrf1"}]}
+  EOT
+}
+
+output "yaml" {
+  value = provider::utils::json_to_yaml(local.json, { indent = 2 })
+}
+
+/* 
+yaml = <<-EOT
+  name: tenant1
+  version: 1.0
+  vrfs:
+    - name: vrf1
+EOT
+*/
@@ -0,0 +1,22 @@
+locals {
+  toml = <<-EOT
+    name = "tenant1"
+
+    [[vrfs]]
+    name = "vrf1"
+    vlan = 10
+  EOT
+}
+
+output "yaml" {
+  value = provider::utils::toml_to_yaml(local.toml)
+}
+
+/* 
+yaml = <<-EOT
+  name: tenant1
+  vrfs:
+      - name: vrf1
+        vlan: 10
+EOT
+*/
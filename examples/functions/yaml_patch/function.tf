@@ -0,0 +1,48 @@
+locals {
+  yaml = <<-EOT
+    root:
+      elem1: value1
+      elem2: value2
+    list:
+      - a1
+      - a2
+  EOT
+
+  json_patch = <<-EOT
+    - op: remove
+      path: /list/0
+    - op: replace
+      path: /root/elem2
+      value: value3
+  EOT
+
+  merge_patch = <<-EOT
+    root:
+      elem1: null
+  EOT
+}
+
+output "json_patch" {
+  value = provider::utils::yaml_patch(local.yaml, local.json_patch)
+}
+
+output "merge_patch" {
+  value = provider::utils::yaml_patch(local.yaml, local.merge_patch)
+}
+
+/* 
+json_patch = <<-EOT
+  list:
+      - a2
+  root:
+      elem1: value1
+      elem2: value3
+EOT
+merge_patch = <<-EOT
+  list:
+      - a1
+      - a2
+  root:
+      elem2: value2
+EOT
+*/
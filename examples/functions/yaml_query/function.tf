@@ -0,0 +1,31 @@
+locals {
+  yaml = <<-EOT
+    tenants:
+      - name: prod
+        vrfs:
+          - name: v1
+          - name: v2
+      - name: dev
+        vrfs:
+          - name: v3
+  EOT
+}
+
+output "vrfs" {
+  value = provider::utils::yaml_query(local.yaml, ".tenants[] | select(.name == \"prod\") | .vrfs[].name")
+}
+
+output "yaml" {
+  value = provider::utils::yaml_query(local.yaml, ".tenants[] | select(.name == \"prod\") | .vrfs", "yaml")
+}
+
+/* 
+vrfs = [
+  "v1",
+  "v2",
+]
+yaml = <<-EOT
+  - - name: v1
+    - name: v2
+EOT
+*/
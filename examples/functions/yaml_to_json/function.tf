@@ -0,0 +1,26 @@
+locals {
+  yaml = <<-EOT
+    name: tenant1
+    version: 1.0
+    vrfs:
+      - name: vrf1
+  EOT
+}
+
+output "json" {
+  value = provider::utils::yaml_to_json(local.yaml, { pretty = true })
+}
+
+/* 
+json = <<-EOT
+  {
+    "name": "tenant1",
+    "version": 1.0,
+    "vrfs": [
+      {
+        "name": "vrf1"
+      }
+    ]
+  }
+EOT
+*/
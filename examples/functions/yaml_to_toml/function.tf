@@ -0,0 +1,22 @@
+locals {
+  yaml = <<-EOT
+    name: tenant1
+    vrfs:
+      - name: vrf1
+        vlan: 10
+  EOT
+}
+
+output "toml" {
+  value = provider::utils::yaml_to_toml(local.yaml)
+}
+
+/* 
+toml = <<-EOT
+  name = "tenant1"
+
+  [[vrfs]]
+  name = "vrf1"
+  vlan = 10
+EOT
+*/
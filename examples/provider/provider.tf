@@ -1,2 +1,9 @@
 provider "utils" {
+  env_files = [".env"]
+
+  tag {
+    name   = "site_prefix"
+    lookup = { zurich = "ZRH", geneva = "GVA" }
+    prefix = "site-"
+  }
 }
@@ -0,0 +1,21 @@
+locals {
+  yaml_1 = <<-EOT
+    root:
+      elem1: value1
+      child1:
+        cc1: 1
+  EOT
+
+  yaml_2 = <<-EOT
+    root:
+      elem2: value2
+      child1:
+        cc2: 2
+  EOT
+}
+
+resource "utils_yaml_file" "example" {
+  input           = [local.yaml_1, local.yaml_2]
+  filename        = "${path.module}/config/merged.yaml"
+  file_permission = "0640"
+}
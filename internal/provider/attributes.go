@@ -0,0 +1,25 @@
+package provider
+
+import (
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+)
+
+// attributeSpec is the description and usage of an attribute shared by the
+// schemas of data sources, resources and ephemeral resources.
+type attributeSpec struct {
+	Description string
+	Required    bool
+	Optional    bool
+	Computed    bool
+}
+
+// attributeBuilder builds the attributes of one kind of schema, e.g. data
+// source or resource attributes, from attribute specs.
+type attributeBuilder[A any] struct {
+	String  func(s attributeSpec) A
+	Bool    func(s attributeSpec) A
+	Int64   func(s attributeSpec) A
+	List    func(s attributeSpec, elementType attr.Type) A
+	Map     func(s attributeSpec, elementType attr.Type) A
+	Dynamic func(s attributeSpec) A
+}
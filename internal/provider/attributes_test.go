@@ -0,0 +1,52 @@
+package provider
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/ephemeral"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+)
+
+// The `utils_yaml_merge` data source, the `utils_yaml_file` resource and the
+// `utils_yaml_merge` ephemeral resource share the same attributes, except for
+// the id of the ephemeral resource.
+func TestYamlMergeAttributes(t *testing.T) {
+	ctx := context.Background()
+	dsResp := &datasource.SchemaResponse{}
+	NewYamlMergeDataSource().Schema(ctx, datasource.SchemaRequest{}, dsResp)
+	rResp := &resource.SchemaResponse{}
+	NewYamlFileResource().Schema(ctx, resource.SchemaRequest{}, rResp)
+	eResp := &ephemeral.SchemaResponse{}
+	NewYamlMergeEphemeralResource().Schema(ctx, ephemeral.SchemaRequest{}, eResp)
+
+	diags := append(dsResp.Schema.ValidateImplementation(ctx), rResp.Schema.ValidateImplementation(ctx)...)
+	diags = append(diags, eResp.Schema.ValidateImplementation(ctx)...)
+	if diags.HasError() {
+		t.Fatalf("Error validating schemas: %#v", diags.Errors())
+	}
+	if _, ok := eResp.Schema.Attributes["id"]; ok {
+		t.Fatalf("Error matching attributes: id of ephemeral resource")
+	}
+
+	for name, attribute := range dsResp.Schema.Attributes {
+		r, ok := rResp.Schema.Attributes[name]
+		if !ok {
+			t.Fatalf("Error matching attributes: %s missing in resource", name)
+		}
+		if !r.GetType().Equal(attribute.GetType()) || r.GetDescription() != attribute.GetDescription() || r.IsRequired() != attribute.IsRequired() || r.IsOptional() != attribute.IsOptional() || r.IsComputed() != attribute.IsComputed() {
+			t.Fatalf("Error matching attribute %s: %#v vs %#v", name, r, attribute)
+		}
+		if name == "id" {
+			continue
+		}
+		e, ok := eResp.Schema.Attributes[name]
+		if !ok {
+			t.Fatalf("Error matching attributes: %s missing in ephemeral resource", name)
+		}
+		if !e.GetType().Equal(attribute.GetType()) || e.GetDescription() != attribute.GetDescription() || e.IsRequired() != attribute.IsRequired() || e.IsOptional() != attribute.IsOptional() || e.IsComputed() != attribute.IsComputed() {
+			t.Fatalf("Error matching attribute %s: %#v vs %#v", name, e, attribute)
+		}
+	}
+}
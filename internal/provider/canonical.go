@@ -0,0 +1,27 @@
+package provider
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// CanonicalYaml encodes v in a canonical form, where keys are sorted
+// alphabetically, scalars use their normalized representation in the default
+// style of the YAML encoder, which is plain unless quotes are required, e.g.
+// `16` for `0x10`, `abc` for `'abc'` and `"123"` for the string `'123'`, and
+// the indentation is 2 spaces. Values which are semantically equal have the
+// same canonical form regardless of the formatting of their input.
+func CanonicalYaml(v interface{}) ([]byte, error) {
+	return YamlMarshalWithOptions(v, YamlMarshalOptions{Indent: 2, SortKeys: "alpha"})
+}
+
+// CanonicalHash returns the hexadecimal encoding of the SHA-256 checksum of
+// the canonical form of v.
+func CanonicalHash(v interface{}) (string, error) {
+	b, err := CanonicalYaml(v)
+	if err != nil {
+		return "", err
+	}
+	checksum := sha256.Sum256(b)
+	return hex.EncodeToString(checksum[:]), nil
+}
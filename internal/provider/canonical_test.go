@@ -0,0 +1,86 @@
+package provider
+
+import (
+	"testing"
+)
+
+func TestCanonicalYaml(t *testing.T) {
+	cases := []struct {
+		input    string
+		expected string
+	}{
+		{
+			input:    "b: 'value'\na:\n    d: 0x10\n    c: \"1.50\"\n",
+			expected: "a:\n  c: \"1.50\"\n  d: 16\nb: value\n",
+		},
+		{
+			input:    "list: [1, 2.0, ~, yes]\n",
+			expected: "list:\n  - 1\n  - 2.0\n  - null\n  - \"yes\"\n",
+		},
+		{
+			input:    "a10: 1\na9: 2\nB: 3\n",
+			expected: "B: 3\na10: 1\na9: 2\n",
+		},
+		{
+			input:    "date: 2001-12-14\n",
+			expected: "date: 2001-12-14T00:00:00Z\n",
+		},
+	}
+
+	for _, c := range cases {
+		var data interface{}
+		err := YamlUnmarshal([]byte(c.input), &data)
+		if err != nil {
+			t.Fatalf("Error reading YAML string: %s", err)
+		}
+		output, err := CanonicalYaml(data)
+		if err != nil {
+			t.Fatalf("Error converting result to YAML: %s", err)
+		}
+		if string(output) != c.expected {
+			t.Fatalf("Error matching output: %q vs %q", string(output), c.expected)
+		}
+	}
+}
+
+func TestCanonicalHash(t *testing.T) {
+	cases := []struct {
+		input1 string
+		input2 string
+		equal  bool
+	}{
+		{
+			input1: "a: 1\nb: [x, y]\n",
+			input2: "b:\n    - 'x'\n    - \"y\"\na: 0x1\n",
+			equal:  true,
+		},
+		{
+			input1: "a: 1\n",
+			input2: "a: \"1\"\n",
+			equal:  false,
+		},
+		{
+			input1: "b: [x, y]\n",
+			input2: "b: [y, x]\n",
+			equal:  false,
+		},
+	}
+
+	for _, c := range cases {
+		var hashes [2]string
+		for j, input := range []string{c.input1, c.input2} {
+			var data interface{}
+			err := YamlUnmarshal([]byte(input), &data)
+			if err != nil {
+				t.Fatalf("Error reading YAML string: %s", err)
+			}
+			hashes[j], err = CanonicalHash(data)
+			if err != nil {
+				t.Fatalf("Error hashing YAML: %s", err)
+			}
+		}
+		if (hashes[0] == hashes[1]) != c.equal {
+			t.Fatalf("Error matching hashes of %q and %q: %s vs %s", c.input1, c.input2, hashes[0], hashes[1])
+		}
+	}
+}
@@ -0,0 +1,188 @@
+package provider
+
+import (
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// computationResolvers returns the resolvers of tags transforming values.
+// Their arguments are resolved first, so that tags can be nested, e.g.
+// `!join [",", !split [";", !env HOSTS]]`.
+func (i *CustomTagProcessor) computationResolvers() map[string]func(*yaml.Node) (*yaml.Node, error) {
+	return map[string]func(*yaml.Node) (*yaml.Node, error){
+		// !concat [list1, list2, ...] concatenates lists, scalars are added as items
+		"!concat": func(node *yaml.Node) (*yaml.Node, error) {
+			args, err := i.resolveArgs(node, 0)
+			if err != nil {
+				return nil, err
+			}
+			result := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq", Line: node.Line, Column: node.Column}
+			for _, arg := range args {
+				if arg.Kind == yaml.SequenceNode {
+					result.Content = append(result.Content, arg.Content...)
+				} else {
+					result.Content = append(result.Content, arg)
+				}
+			}
+			return result, nil
+		},
+		// !join [separator, items...] joins items, lists are flattened
+		"!join": func(node *yaml.Node) (*yaml.Node, error) {
+			args, err := i.resolveValueArgs(node, 1)
+			if err != nil {
+				return nil, err
+			}
+			if args[0].Kind != yaml.ScalarNode {
+				return nil, fmt.Errorf("line %d: !join requires a separator", node.Line)
+			}
+			strs, err := computationStrings(node, args)
+			if err != nil {
+				return nil, err
+			}
+			return computationScalar(node, strings.Join(strs[1:], strs[0])), nil
+		},
+		// !split [separator, string] splits a string into a list
+		"!split": func(node *yaml.Node) (*yaml.Node, error) {
+			args, err := i.resolveValueArgs(node, 2)
+			if err != nil {
+				return nil, err
+			}
+			if len(args) != 2 || args[0].Kind != yaml.ScalarNode || args[1].Kind != yaml.ScalarNode {
+				return nil, fmt.Errorf("line %d: !split requires a separator and a string", node.Line)
+			}
+			result := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq", Line: node.Line, Column: node.Column}
+			for _, s := range strings.Split(args[1].Value, args[0].Value) {
+				result.Content = append(result.Content, computationScalar(node, s))
+			}
+			return result, nil
+		},
+		"!upper": func(node *yaml.Node) (*yaml.Node, error) {
+			s, err := i.resolveStringArg(node)
+			if err != nil {
+				return nil, err
+			}
+			return computationScalar(node, strings.ToUpper(s)), nil
+		},
+		"!lower": func(node *yaml.Node) (*yaml.Node, error) {
+			s, err := i.resolveStringArg(node)
+			if err != nil {
+				return nil, err
+			}
+			return computationScalar(node, strings.ToLower(s)), nil
+		},
+		// !format [format, args...] formats args like fmt.Sprintf
+		"!format": func(node *yaml.Node) (*yaml.Node, error) {
+			args, err := i.resolveValueArgs(node, 1)
+			if err != nil {
+				return nil, err
+			}
+			if args[0].Kind != yaml.ScalarNode {
+				return nil, fmt.Errorf("line %d: !format requires a format string", node.Line)
+			}
+			values := make([]interface{}, len(args)-1)
+			for j, arg := range args[1:] {
+				err = arg.Decode(&values[j])
+				if err != nil {
+					return nil, fmt.Errorf("line %d: !format: %s", node.Line, err)
+				}
+			}
+			return computationScalar(node, fmt.Sprintf(args[0].Value, values...)), nil
+		},
+	}
+}
+
+// resolveArgs resolves the items of a sequence node used as arguments of a
+// computation tag.
+func (i *CustomTagProcessor) resolveArgs(node *yaml.Node, minArgs int) ([]*yaml.Node, error) {
+	if node.Kind != yaml.SequenceNode {
+		return nil, fmt.Errorf("line %d: %s on a non-sequence node", node.Line, node.Tag)
+	}
+	if len(node.Content) < minArgs {
+		return nil, fmt.Errorf("line %d: %s: too few arguments", node.Line, node.Tag)
+	}
+	args := make([]*yaml.Node, len(node.Content))
+	for j, arg := range node.Content {
+		resolved, err := i.resolveTags(arg)
+		if err != nil {
+			return nil, err
+		}
+		if resolved.Kind == yaml.AliasNode {
+			resolved = resolved.Alias
+		}
+		args[j] = resolved
+	}
+	return args, nil
+}
+
+// resolveValueArgs resolves the arguments of a computation tag like
+// resolveArgs, but fails if an argument contains a reference. References
+// are resolved after merging, so their values are not available yet.
+func (i *CustomTagProcessor) resolveValueArgs(node *yaml.Node, minArgs int) ([]*yaml.Node, error) {
+	args, err := i.resolveArgs(node, minArgs)
+	if err != nil {
+		return nil, err
+	}
+	for _, arg := range args {
+		if i.hasMarker(arg) {
+			return nil, fmt.Errorf("line %d: %s does not support references as arguments, which are resolved after merging", node.Line, node.Tag)
+		}
+	}
+	return args, nil
+}
+
+// hasMarker returns true if a node or one of its children is a reference.
+func (i *CustomTagProcessor) hasMarker(node *yaml.Node) bool {
+	if _, ok := i.marked(node); ok {
+		return true
+	}
+	for _, child := range node.Content {
+		if i.hasMarker(child) {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveStringArg returns the value of a scalar node or of the only item
+// of a sequence node, e.g. `!upper value` or `!upper [!env NAME]`.
+func (i *CustomTagProcessor) resolveStringArg(node *yaml.Node) (string, error) {
+	if node.Kind == yaml.ScalarNode {
+		return node.Value, nil
+	}
+	args, err := i.resolveValueArgs(node, 1)
+	if err != nil {
+		return "", err
+	}
+	if len(args) != 1 || args[0].Kind != yaml.ScalarNode {
+		return "", fmt.Errorf("line %d: %s requires a single string", node.Line, node.Tag)
+	}
+	return args[0].Value, nil
+}
+
+// computationStrings returns the values of scalar nodes, where the items of
+// sequence nodes are flattened.
+func computationStrings(node *yaml.Node, args []*yaml.Node) ([]string, error) {
+	var strs []string
+	for _, arg := range args {
+		items := []*yaml.Node{arg}
+		if arg.Kind == yaml.SequenceNode {
+			items = arg.Content
+		}
+		for _, item := range items {
+			if item.Kind == yaml.AliasNode {
+				item = item.Alias
+			}
+			if item.Kind != yaml.ScalarNode {
+				return nil, fmt.Errorf("line %d: %s requires strings", node.Line, node.Tag)
+			}
+			strs = append(strs, item.Value)
+		}
+	}
+	return strs, nil
+}
+
+func computationScalar(node *yaml.Node, value string) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value, Line: node.Line, Column: node.Column}
+}
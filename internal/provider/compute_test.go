@@ -0,0 +1,117 @@
+package provider
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestYamlUnmarshalComputations(t *testing.T) {
+	t.Setenv("YAML_TEST_HOSTS", "host1;host2")
+
+	input := `
+hosts: !split [";", !env YAML_TEST_HOSTS]
+joined: !join [",", !split [";", !env YAML_TEST_HOSTS]]
+items: !join ["-", a, [b, c], 1]
+upper: !upper value
+lower: !lower [!env YAML_TEST_HOSTS]
+format: !format ["%s-%03d-%v", !upper vlan, 10, true]
+concat: !concat [[a, b], [c], d, !split [",", "e,f"]]
+nested: !upper [!join ["", !lower ABC, def]]
+`
+	result := map[string]interface{}{
+		"hosts":  []interface{}{"host1", "host2"},
+		"joined": "host1,host2",
+		"items":  "a-b-c-1",
+		"upper":  "VALUE",
+		"lower":  "host1;host2",
+		"format": "VLAN-010-true",
+		"concat": []interface{}{"a", "b", "c", "d", "e", "f"},
+		"nested": "ABCDEF",
+	}
+
+	var data map[string]interface{}
+	err := YamlUnmarshal([]byte(input), &data)
+	if err != nil {
+		t.Fatalf("Error reading YAML string: %s", err)
+	}
+	if !reflect.DeepEqual(data, result) {
+		t.Fatalf("Error matching data and result: %#v vs %#v", data, result)
+	}
+
+	cases := []struct {
+		input string
+		err   string
+	}{
+		{
+			input: "elem1: !join\n  a: b\n",
+			err:   "line 1: !join on a non-sequence node",
+		},
+		{
+			input: "elem1: !join []\n",
+			err:   "line 1: !join: too few arguments",
+		},
+		{
+			input: "elem1: !join [[a], b]\n",
+			err:   "line 1: !join requires a separator",
+		},
+		{
+			input: "elem1: !join [',', {a: b}]\n",
+			err:   "line 1: !join requires strings",
+		},
+		{
+			input: "elem1: !split [a]\n",
+			err:   "line 1: !split: too few arguments",
+		},
+		{
+			input: "elem1: !upper [a, b]\n",
+			err:   "line 1: !upper requires a single string",
+		},
+		{
+			input: "elem1: !format [[a]]\n",
+			err:   "line 1: !format requires a format string",
+		},
+		{
+			input: "a: x\nelem1: !join [\",\", !ref a, y]\n",
+			err:   "line 2: !join does not support references as arguments",
+		},
+		{
+			input: "a: x\nelem1: !join [\",\", [x, !ref a]]\n",
+			err:   "line 2: !join does not support references as arguments",
+		},
+		{
+			input: "a: x\nelem1: !split [\",\", !ref a]\n",
+			err:   "line 2: !split does not support references as arguments",
+		},
+		{
+			input: "a: x\nelem1: !upper [!ref a]\n",
+			err:   "line 2: !upper does not support references as arguments",
+		},
+		{
+			input: "a: x\nelem1: !lower [!ref a]\n",
+			err:   "line 2: !lower does not support references as arguments",
+		},
+		{
+			input: "a: x\nelem1: !format [\"%s\", !ref a]\n",
+			err:   "line 2: !format does not support references as arguments",
+		},
+	}
+
+	// references are kept by !concat and resolved after merging
+	data = nil
+	err = YamlUnmarshal([]byte("a: x\nconcat: !concat [[!ref a], b]\n"), &data)
+	if err == nil {
+		_, err = ResolveRefs(data)
+	}
+	if err != nil || !reflect.DeepEqual(data["concat"], []interface{}{"x", "b"}) {
+		t.Fatalf("Error matching references of !concat: %#v: %v", data["concat"], err)
+	}
+
+	for _, c := range cases {
+		var data map[string]interface{}
+		err := YamlUnmarshal([]byte(c.input), &data)
+		if err == nil || !strings.Contains(err.Error(), c.err) {
+			t.Fatalf("Error matching error: %v vs %s", err, c.err)
+		}
+	}
+}
@@ -0,0 +1,254 @@
+package provider
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"gopkg.in/yaml.v3"
+)
+
+// resolveCondition evaluates the condition of a `!if` or `!unless` node and
+// returns the wrapped value node and whether it is kept. Conditional nodes
+// are maps with a `condition` and a `value` key, e.g.
+//
+//	bgp: !if
+//	  condition: env.STAGE == "prod" && ref:features.bgp
+//	  value:
+//	    asn: 65000
+func (i *CustomTagProcessor) resolveCondition(node *yaml.Node) (*yaml.Node, bool, error) {
+	if node.Kind != yaml.MappingNode {
+		return nil, false, fmt.Errorf("line %d: %s on a non-mapping node", node.Line, node.Tag)
+	}
+	var condition, value *yaml.Node
+	for j := 0; j+1 < len(node.Content); j += 2 {
+		switch node.Content[j].Value {
+		case "condition":
+			condition = node.Content[j+1]
+		case "value":
+			value = node.Content[j+1]
+		default:
+			return nil, false, fmt.Errorf("line %d: %s: unknown key '%s'", node.Content[j].Line, node.Tag, node.Content[j].Value)
+		}
+	}
+	if condition == nil || condition.Kind != yaml.ScalarNode || value == nil {
+		return nil, false, fmt.Errorf("line %d: %s requires a 'condition' and a 'value'", node.Line, node.Tag)
+	}
+	result, err := evaluateCondition(condition.Value, i.options.Refs, i.getenv)
+	if err != nil {
+		return nil, false, fmt.Errorf("line %d: %s: %s", condition.Line, node.Tag, err)
+	}
+	if node.Tag == "!unless" {
+		result = !result
+	}
+	return value, result, nil
+}
+
+func isConditional(node *yaml.Node) bool {
+	return node.Tag == "!if" || node.Tag == "!unless"
+}
+
+// EvaluateCondition evaluates a condition of a `!if` or `!unless` tag.
+// Operands are environment variables (`env.NAME`), references to values of
+// refs (`ref:path.to.value`), strings, numbers, `true`, `false` and `null`.
+// Environment variables are read from the process environment, conditions of
+// YAML tags use the env files and variables of the YAML options instead.
+// Environment variables which are not set and missing references are `null`.
+// Supported operators are `==`, `!=`, `&&`, `||`, `!` and parentheses. A
+// single operand is true if it exists and is not `false`.
+func EvaluateCondition(condition string, refs interface{}) (bool, error) {
+	return evaluateCondition(condition, refs, func(name string) string {
+		return getenv(nil, name)
+	})
+}
+
+func evaluateCondition(condition string, refs interface{}, env func(string) string) (bool, error) {
+	tokens, err := tokenizeCondition(condition)
+	if err != nil {
+		return false, err
+	}
+	p := &conditionParser{tokens: tokens, refs: refs, env: env}
+	result, err := p.parseOr()
+	if err != nil {
+		return false, err
+	}
+	if p.pos < len(p.tokens) {
+		return false, fmt.Errorf("unexpected '%s' at position %d", p.tokens[p.pos].value, p.tokens[p.pos].pos)
+	}
+	return queryTruthy(result), nil
+}
+
+func tokenizeCondition(condition string) ([]queryToken, error) {
+	var tokens []queryToken
+	for i := 0; i < len(condition); {
+		c := condition[i]
+		switch {
+		case unicode.IsSpace(rune(c)):
+			i++
+		case c == '"' || c == '\'':
+			end := strings.IndexByte(condition[i+1:], c)
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated string at position %d", i)
+			}
+			tokens = append(tokens, queryToken{queryTokenString, condition[i+1 : i+1+end], i})
+			i += end + 2
+		case unicode.IsDigit(rune(c)) || c == '-':
+			j := i + 1
+			for j < len(condition) && (unicode.IsDigit(rune(condition[j])) || condition[j] == '.') {
+				j++
+			}
+			tokens = append(tokens, queryToken{queryTokenNumber, condition[i:j], i})
+			i = j
+		case c == '_' || unicode.IsLetter(rune(c)):
+			// identifiers include the path of env and ref operands
+			j := i + 1
+			for j < len(condition) && (strings.IndexByte("_-.:", condition[j]) >= 0 || unicode.IsLetter(rune(condition[j])) || unicode.IsDigit(rune(condition[j]))) {
+				j++
+			}
+			tokens = append(tokens, queryToken{queryTokenIdent, condition[i:j], i})
+			i = j
+		default:
+			matched := false
+			for _, op := range []string{"==", "!=", "&&", "||", "!", "(", ")"} {
+				if strings.HasPrefix(condition[i:], op) {
+					tokens = append(tokens, queryToken{queryTokenPunct, op, i})
+					i += len(op)
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				return nil, fmt.Errorf("unexpected character '%c' at position %d", c, i)
+			}
+		}
+	}
+	return tokens, nil
+}
+
+type conditionParser struct {
+	tokens []queryToken
+	pos    int
+	refs   interface{}
+	env    func(string) string
+}
+
+func (p *conditionParser) accept(op string) bool {
+	if p.pos < len(p.tokens) && p.tokens[p.pos].kind == queryTokenPunct && p.tokens[p.pos].value == op {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *conditionParser) parseOr() (interface{}, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("||") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = queryTruthy(left) || queryTruthy(right)
+	}
+	return left, nil
+}
+
+func (p *conditionParser) parseAnd() (interface{}, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("&&") {
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = queryTruthy(left) && queryTruthy(right)
+	}
+	return left, nil
+}
+
+func (p *conditionParser) parseNot() (interface{}, error) {
+	if p.accept("!") {
+		v, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return !queryTruthy(v), nil
+	}
+	return p.parseCompare()
+}
+
+func (p *conditionParser) parseCompare() (interface{}, error) {
+	left, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	if p.accept("==") {
+		right, err := p.parseOperand()
+		if err != nil {
+			return nil, err
+		}
+		return jsonEqual(left, right), nil
+	}
+	if p.accept("!=") {
+		right, err := p.parseOperand()
+		if err != nil {
+			return nil, err
+		}
+		return !jsonEqual(left, right), nil
+	}
+	return left, nil
+}
+
+func (p *conditionParser) parseOperand() (interface{}, error) {
+	if p.accept("(") {
+		v, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if !p.accept(")") {
+			return nil, fmt.Errorf("missing ')'")
+		}
+		return v, nil
+	}
+	if p.pos >= len(p.tokens) {
+		return nil, fmt.Errorf("unexpected end of condition")
+	}
+	token := p.tokens[p.pos]
+	p.pos++
+	switch token.kind {
+	case queryTokenString:
+		return token.value, nil
+	case queryTokenNumber:
+		f, err := strconv.ParseFloat(token.value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number '%s' at position %d", token.value, token.pos)
+		}
+		return f, nil
+	case queryTokenIdent:
+		switch {
+		case token.value == "true":
+			return true, nil
+		case token.value == "false":
+			return false, nil
+		case token.value == "null":
+			return nil, nil
+		case strings.HasPrefix(token.value, "env."):
+			if value := p.env(token.value[4:]); value != "" {
+				return value, nil
+			}
+			return nil, nil
+		case strings.HasPrefix(token.value, "ref:"):
+			v, err := jsonPatchGet(p.refs, strings.Split(token.value[4:], "."))
+			if err != nil {
+				return nil, nil
+			}
+			return v, nil
+		}
+	}
+	return nil, fmt.Errorf("unexpected '%s' at position %d", token.value, token.pos)
+}
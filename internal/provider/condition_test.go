@@ -0,0 +1,127 @@
+package provider
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestEvaluateCondition(t *testing.T) {
+	t.Setenv("YAML_TEST_STAGE", "prod")
+
+	refs := map[interface{}]interface{}{
+		"features": map[interface{}]interface{}{"bgp": true, "ospf": false, "count": 2},
+	}
+
+	cases := []struct {
+		condition string
+		result    bool
+	}{
+		{`env.YAML_TEST_STAGE == "prod"`, true},
+		{`env.YAML_TEST_STAGE != 'prod'`, false},
+		{`env.YAML_TEST_STAGE`, true},
+		{`env.YAML_TEST_MISSING`, false},
+		{`env.YAML_TEST_MISSING == null`, true},
+		{`ref:features.bgp`, true},
+		{`ref:features.ospf`, false},
+		{`ref:features.missing`, false},
+		{`ref:features.count == 2`, true},
+		{`!ref:features.ospf && ref:features.bgp`, true},
+		{`ref:features.ospf || env.YAML_TEST_STAGE == "dev"`, false},
+		{`!(ref:features.ospf || env.YAML_TEST_STAGE == "dev")`, true},
+		{`true && (false || true)`, true},
+	}
+
+	for _, c := range cases {
+		result, err := EvaluateCondition(c.condition, refs)
+		if err != nil {
+			t.Fatalf("Error evaluating condition '%s': %s", c.condition, err)
+		}
+		if result != c.result {
+			t.Fatalf("Error matching condition '%s': %v vs %v", c.condition, result, c.result)
+		}
+	}
+
+	for _, condition := range []string{`env.A ==`, `(true`, `"a`, `a && b`, `true false`, `1 # 2`} {
+		_, err := EvaluateCondition(condition, refs)
+		if err == nil {
+			t.Fatalf("Error expected for condition '%s'", condition)
+		}
+	}
+}
+
+func TestYamlUnmarshalConditions(t *testing.T) {
+	t.Setenv("YAML_TEST_STAGE", "prod")
+
+	input := `
+bgp: !if
+  condition: env.YAML_TEST_STAGE == "prod"
+  value:
+    asn: 65000
+ospf: !if
+  condition: ref:features.ospf
+  value:
+    area: 0
+dev: !unless
+  condition: env.YAML_TEST_STAGE == "prod"
+  value: true
+list:
+  - elem1
+  - !if
+    condition: ref:features.bgp
+    value: elem2
+  - !unless
+    condition: ref:features.bgp
+    value: elem3
+`
+	result := map[string]interface{}{
+		"bgp":  map[string]interface{}{"asn": 65000},
+		"list": []interface{}{"elem1", "elem2"},
+	}
+	refs := map[interface{}]interface{}{
+		"features": map[interface{}]interface{}{"bgp": true},
+	}
+
+	var data map[string]interface{}
+	err := YamlUnmarshalWithOptions([]byte(input), &data, YamlOptions{Refs: refs})
+	if err != nil {
+		t.Fatalf("Error reading YAML string: %s", err)
+	}
+	if !reflect.DeepEqual(data, result) {
+		t.Fatalf("Error matching data and result: %#v vs %#v", data, result)
+	}
+
+	cases := []struct {
+		input string
+		err   string
+	}{
+		{
+			input: "elem1: !if\n  value: 1\n",
+			err:   "line 1: !if requires a 'condition' and a 'value'",
+		},
+		{
+			input: "elem1: !if\n  condition: true\n  other: 1\n",
+			err:   "line 3: !if: unknown key 'other'",
+		},
+		{
+			input: "elem1: !unless\n  condition: (true\n  value: 1\n",
+			err:   "line 2: !unless: missing ')'",
+		},
+		{
+			input: "elem1: !if value\n",
+			err:   "line 1: !if on a non-mapping node",
+		},
+		{
+			input: "!if {condition: true, value: {a: b}}\n",
+			err:   "!if is only supported for mapping values and sequence items",
+		},
+	}
+
+	for _, c := range cases {
+		var data map[string]interface{}
+		err := YamlUnmarshal([]byte(c.input), &data)
+		if err == nil || !strings.Contains(err.Error(), c.err) {
+			t.Fatalf("Error matching error: %v vs %s", err, c.err)
+		}
+	}
+}
@@ -0,0 +1,368 @@
+package provider
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ConvertOptions controls the output of conversion functions.
+type ConvertOptions struct {
+	// Pretty indents JSON output, otherwise it is compact.
+	Pretty bool
+	// Indent is the number of spaces used for indentation of JSON and YAML
+	// output, defaults to 2 for JSON and 4 for YAML.
+	Indent int
+	// SortKeys is the order of keys: `first_seen` keeps the order of the
+	// input, `alpha` sorts keys alphabetically and `none` uses the natural
+	// order of the YAML encoder.
+	SortKeys string
+}
+
+// ParseConvertOptions reads conversion options from a map with the optional
+// keys `pretty`, `indent` and `sort_keys`.
+func ParseConvertOptions(v interface{}) (ConvertOptions, error) {
+	options := ConvertOptions{SortKeys: "first_seen"}
+	if v == nil {
+		return options, nil
+	}
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return options, fmt.Errorf("options must be an object")
+	}
+	for key, value := range m {
+		var valid bool
+		switch key {
+		case "pretty":
+			options.Pretty, valid = value.(bool)
+		case "indent":
+			var indent int64
+			indent, valid = value.(int64)
+			options.Indent = int(indent)
+			valid = valid && indent >= 1 && indent <= 9
+		case "sort_keys":
+			options.SortKeys, valid = value.(string)
+			valid = valid && (options.SortKeys == "none" || options.SortKeys == "alpha" || options.SortKeys == "first_seen")
+		default:
+			return options, fmt.Errorf("unknown option '%s'", key)
+		}
+		if !valid {
+			return options, fmt.Errorf("invalid value of option '%s'", key)
+		}
+	}
+	return options, nil
+}
+
+// MarshalWithFormat encodes v as a YAML, JSON or TOML string. The original
+// representation of scalars and the order of keys are taken from styles.
+func MarshalWithFormat(format string, v interface{}, styles *ScalarStyles, options ConvertOptions) ([]byte, error) {
+	marshalOptions := YamlMarshalOptions{Styles: styles, Indent: options.Indent, SortKeys: options.SortKeys}
+	if format == "yaml" {
+		return YamlMarshalWithOptions(v, marshalOptions)
+	}
+	node, err := encodeNode(v, marshalOptions)
+	if err != nil {
+		return nil, err
+	}
+	var b bytes.Buffer
+	switch format {
+	case "json":
+		indent := ""
+		if options.Pretty {
+			indent = "  "
+			if options.Indent > 0 {
+				indent = strings.Repeat(" ", options.Indent)
+			}
+		}
+		err = writeJSON(&b, node, indent, "")
+	case "toml":
+		if node.Kind != yaml.MappingNode {
+			return nil, fmt.Errorf("toml: only maps can be converted to TOML")
+		}
+		err = writeTOMLTable(&b, node, nil)
+	default:
+		return nil, fmt.Errorf("unknown format '%s'", format)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+var jsonNumberRegex = regexp.MustCompile(`^-?(0|[1-9][0-9]*)(\.[0-9]+)?([eE][+-]?[0-9]+)?$`)
+
+// writeJSON writes node as JSON, where nested values are indented with
+// indent if it is not empty.
+func writeJSON(b *bytes.Buffer, node *yaml.Node, indent, prefix string) error {
+	newline := func(prefix string) {
+		if indent != "" {
+			b.WriteString("\n" + prefix)
+		}
+	}
+	switch node.Kind {
+	case yaml.AliasNode:
+		return writeJSON(b, node.Alias, indent, prefix)
+	case yaml.MappingNode:
+		if len(node.Content) == 0 {
+			b.WriteString("{}")
+			return nil
+		}
+		b.WriteByte('{')
+		for j := 0; j+1 < len(node.Content); j += 2 {
+			if j > 0 {
+				b.WriteByte(',')
+			}
+			newline(prefix + indent)
+			key := node.Content[j]
+			if key.Kind != yaml.ScalarNode {
+				return fmt.Errorf("json: only scalar keys can be converted to JSON")
+			}
+			s, err := jsonEncode(key.Value)
+			if err != nil {
+				return err
+			}
+			b.Write(s)
+			b.WriteByte(':')
+			if indent != "" {
+				b.WriteByte(' ')
+			}
+			err = writeJSON(b, node.Content[j+1], indent, prefix+indent)
+			if err != nil {
+				return err
+			}
+		}
+		newline(prefix)
+		b.WriteByte('}')
+	case yaml.SequenceNode:
+		if len(node.Content) == 0 {
+			b.WriteString("[]")
+			return nil
+		}
+		b.WriteByte('[')
+		for j, item := range node.Content {
+			if j > 0 {
+				b.WriteByte(',')
+			}
+			newline(prefix + indent)
+			err := writeJSON(b, item, indent, prefix+indent)
+			if err != nil {
+				return err
+			}
+		}
+		newline(prefix)
+		b.WriteByte(']')
+	case yaml.ScalarNode:
+		var v interface{}
+		err := node.Decode(&v)
+		if err != nil {
+			return err
+		}
+		switch v.(type) {
+		case int, int64, uint64, float64:
+			// keep the representation of numbers like `1.0` or `1e3`
+			if jsonNumberRegex.MatchString(node.Value) {
+				b.WriteString(node.Value)
+				return nil
+			}
+		}
+		s, err := jsonEncode(v)
+		if err != nil {
+			return err
+		}
+		b.Write(s)
+	}
+	return nil
+}
+
+// jsonEncode encodes v as JSON without escaping HTML characters.
+func jsonEncode(v interface{}) ([]byte, error) {
+	var b bytes.Buffer
+	encoder := json.NewEncoder(&b)
+	encoder.SetEscapeHTML(false)
+	err := encoder.Encode(v)
+	if err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(b.Bytes(), []byte("\n")), nil
+}
+
+// writeTOMLTable writes the entries of a map node as TOML table, where maps
+// and lists of maps are written as tables and arrays of tables after all
+// other values. TOML has no null values, so they are omitted.
+func writeTOMLTable(b *bytes.Buffer, node *yaml.Node, path []string) error {
+	var tables []int
+	for j := 0; j+1 < len(node.Content); j += 2 {
+		key, value := node.Content[j], node.Content[j+1]
+		if isTOMLTable(value) || isTOMLArrayOfTables(value) {
+			tables = append(tables, j)
+			continue
+		}
+		if value.ShortTag() == "!!null" {
+			continue
+		}
+		b.WriteString(tomlKey(key.Value) + " = ")
+		err := writeTOMLValue(b, value)
+		if err != nil {
+			return err
+		}
+		b.WriteByte('\n')
+	}
+	for _, j := range tables {
+		key, value := node.Content[j], node.Content[j+1]
+		tablePath := append(path[:len(path):len(path)], tomlKey(key.Value))
+		items := []*yaml.Node{value}
+		header := "[" + strings.Join(tablePath, ".") + "]"
+		if value.Kind == yaml.SequenceNode {
+			items = value.Content
+			header = "[" + header + "]"
+		}
+		for _, item := range items {
+			if b.Len() > 0 {
+				b.WriteByte('\n')
+			}
+			b.WriteString(header + "\n")
+			err := writeTOMLTable(b, item, tablePath)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func isTOMLTable(node *yaml.Node) bool {
+	return node.Kind == yaml.MappingNode
+}
+
+func isTOMLArrayOfTables(node *yaml.Node) bool {
+	if node.Kind != yaml.SequenceNode || len(node.Content) == 0 {
+		return false
+	}
+	for _, item := range node.Content {
+		if item.Kind != yaml.MappingNode {
+			return false
+		}
+	}
+	return true
+}
+
+// writeTOMLValue writes node as inline TOML value.
+func writeTOMLValue(b *bytes.Buffer, node *yaml.Node) error {
+	switch node.Kind {
+	case yaml.AliasNode:
+		return writeTOMLValue(b, node.Alias)
+	case yaml.MappingNode:
+		b.WriteByte('{')
+		first := true
+		for j := 0; j+1 < len(node.Content); j += 2 {
+			if node.Content[j+1].ShortTag() == "!!null" {
+				continue
+			}
+			if !first {
+				b.WriteByte(',')
+			}
+			first = false
+			b.WriteString(" " + tomlKey(node.Content[j].Value) + " = ")
+			err := writeTOMLValue(b, node.Content[j+1])
+			if err != nil {
+				return err
+			}
+		}
+		if !first {
+			b.WriteByte(' ')
+		}
+		b.WriteByte('}')
+	case yaml.SequenceNode:
+		b.WriteByte('[')
+		for j, item := range node.Content {
+			if j > 0 {
+				b.WriteString(", ")
+			}
+			err := writeTOMLValue(b, item)
+			if err != nil {
+				return err
+			}
+		}
+		b.WriteByte(']')
+	case yaml.ScalarNode:
+		var v interface{}
+		err := node.Decode(&v)
+		if err != nil {
+			return err
+		}
+		switch v := v.(type) {
+		case nil:
+			return fmt.Errorf("toml: null values in lists cannot be converted to TOML")
+		case string:
+			b.WriteString(tomlString(v))
+		case float64:
+			switch {
+			case math.IsInf(v, 1):
+				b.WriteString("inf")
+			case math.IsInf(v, -1):
+				b.WriteString("-inf")
+			case math.IsNaN(v):
+				b.WriteString("nan")
+			default:
+				s := strconv.FormatFloat(v, 'g', -1, 64)
+				if !strings.ContainsAny(s, ".e") {
+					s += ".0"
+				}
+				b.WriteString(s)
+			}
+		case time.Time:
+			b.WriteString(v.Format(time.RFC3339Nano))
+		default:
+			b.WriteString(fmt.Sprint(v))
+		}
+	}
+	return nil
+}
+
+var tomlBareKeyRegex = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+func tomlKey(key string) string {
+	if tomlBareKeyRegex.MatchString(key) {
+		return key
+	}
+	return tomlString(key)
+}
+
+// tomlString returns s as TOML basic string.
+func tomlString(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"':
+			b.WriteString(`\"`)
+		case '\\':
+			b.WriteString(`\\`)
+		case '\b':
+			b.WriteString(`\b`)
+		case '\t':
+			b.WriteString(`\t`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\f':
+			b.WriteString(`\f`)
+		case '\r':
+			b.WriteString(`\r`)
+		default:
+			if r < 0x20 || r == 0x7f {
+				fmt.Fprintf(&b, `\u%04X`, r)
+			} else {
+				b.WriteRune(r)
+			}
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
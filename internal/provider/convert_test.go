@@ -0,0 +1,152 @@
+package provider
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestConvert(t *testing.T) {
+	cases := []struct {
+		from     string
+		to       string
+		input    string
+		options  ConvertOptions
+		expected string
+	}{
+		{
+			from:     "yaml",
+			to:       "json",
+			input:    "name: tenant1\nid: 0x10\nversion: 1.0\nenabled: true\nvrfs:\n  - name: vrf1\n    description: <none>\nempty:\n",
+			options:  ConvertOptions{SortKeys: "first_seen"},
+			expected: `{"name":"tenant1","id":16,"version":1.0,"enabled":true,"vrfs":[{"name":"vrf1","description":"<none>"}],"empty":null}`,
+		},
+		{
+			from:     "yaml",
+			to:       "json",
+			input:    "b: 1\na: [x, {}]\n",
+			options:  ConvertOptions{Pretty: true, SortKeys: "alpha"},
+			expected: "{\n  \"a\": [\n    \"x\",\n    {}\n  ],\n  \"b\": 1\n}",
+		},
+		{
+			from:     "yaml",
+			to:       "json",
+			input:    "date: 2001-12-14\n",
+			options:  ConvertOptions{Pretty: true, Indent: 4, SortKeys: "first_seen"},
+			expected: "{\n    \"date\": \"2001-12-14T00:00:00Z\"\n}",
+		},
+		{
+			from:     "json",
+			to:       "yaml",
+			input:    `{"name": "tenant1", "version": 1.0, "flag": "true", "vrfs": [{"name": "vrf1", "id": 10}]}`,
+			options:  ConvertOptions{Indent: 2, SortKeys: "first_seen"},
+			expected: "name: tenant1\nversion: 1.0\nflag: \"true\"\nvrfs:\n  - name: vrf1\n    id: 10\n",
+		},
+		{
+			from:     "yaml",
+			to:       "toml",
+			input:    "title: test\nempty:\nowner:\n  name: \"Tom \\\"T\\\"\"\n  tags: [a, b]\nservers:\n  - name: alpha\n    ip: 10.0.0.1\n  - name: beta\n    port: 8080\nratio: 2.0\n",
+			options:  ConvertOptions{SortKeys: "first_seen"},
+			expected: "title = \"test\"\nratio = 2.0\n\n[owner]\nname = \"Tom \\\"T\\\"\"\ntags = [\"a\", \"b\"]\n\n[[servers]]\nname = \"alpha\"\nip = \"10.0.0.1\"\n\n[[servers]]\nname = \"beta\"\nport = 8080\n",
+		},
+		{
+			from:     "toml",
+			to:       "yaml",
+			input:    "title = \"test\"\nratio = 2.0\n\n[owner]\nname = \"tom\"\nage = 42\n\n[[servers]]\nname = \"alpha\"\nip = \"10.0.0.1\"\n",
+			options:  ConvertOptions{Indent: 2, SortKeys: "first_seen"},
+			expected: "title: test\nratio: 2.0\nowner:\n  name: tom\n  age: 42\nservers:\n  - name: alpha\n    ip: 10.0.0.1\n",
+		},
+	}
+
+	for _, c := range cases {
+		styles := NewScalarStyles()
+		var data interface{}
+		err := UnmarshalWithFormat(c.from, []byte(c.input), &data, YamlOptions{ScalarStyles: styles})
+		if err != nil {
+			t.Fatalf("Error reading %s string: %s", c.from, err)
+		}
+		output, err := MarshalWithFormat(c.to, data, styles, c.options)
+		if err != nil {
+			t.Fatalf("Error converting result to %s: %s", c.to, err)
+		}
+		if string(output) != c.expected {
+			t.Fatalf("Error matching output: %q vs %q", string(output), c.expected)
+		}
+	}
+}
+
+func TestConvertErrors(t *testing.T) {
+	cases := []struct {
+		to    string
+		input string
+		err   string
+	}{
+		{
+			to:    "toml",
+			input: "- a\n- b\n",
+			err:   "only maps can be converted to TOML",
+		},
+		{
+			to:    "toml",
+			input: "list:\n  - a\n  - null\n",
+			err:   "null values in lists cannot be converted to TOML",
+		},
+		{
+			to:    "json",
+			input: "value: .inf\n",
+			err:   "unsupported value",
+		},
+	}
+
+	for _, c := range cases {
+		var data interface{}
+		err := YamlUnmarshal([]byte(c.input), &data)
+		if err != nil {
+			t.Fatalf("Error reading YAML string: %s", err)
+		}
+		_, err = MarshalWithFormat(c.to, data, nil, ConvertOptions{})
+		if err == nil || !strings.Contains(err.Error(), c.err) {
+			t.Fatalf("Error matching error: %v vs %s", err, c.err)
+		}
+	}
+}
+
+func TestParseConvertOptions(t *testing.T) {
+	cases := []struct {
+		input    interface{}
+		expected ConvertOptions
+		err      string
+	}{
+		{
+			input:    nil,
+			expected: ConvertOptions{SortKeys: "first_seen"},
+		},
+		{
+			input:    map[string]interface{}{"pretty": true, "indent": int64(4), "sort_keys": "alpha"},
+			expected: ConvertOptions{Pretty: true, Indent: 4, SortKeys: "alpha"},
+		},
+		{
+			input: map[string]interface{}{"sort_keys": "random"},
+			err:   "invalid value of option 'sort_keys'",
+		},
+		{
+			input: map[string]interface{}{"width": int64(80)},
+			err:   "unknown option 'width'",
+		},
+	}
+
+	for _, c := range cases {
+		options, err := ParseConvertOptions(c.input)
+		if c.err != "" {
+			if err == nil || !strings.Contains(err.Error(), c.err) {
+				t.Fatalf("Error matching error: %v vs %s", err, c.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("Error parsing options: %s", err)
+		}
+		if options != c.expected {
+			t.Fatalf("Error matching options: %#v vs %#v", options, c.expected)
+		}
+	}
+}
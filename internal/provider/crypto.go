@@ -0,0 +1,146 @@
+package provider
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"golang.org/x/crypto/blowfish"
+	"gopkg.in/yaml.v3"
+)
+
+// uuidNamespaces are the predefined UUID namespaces of RFC 4122.
+var uuidNamespaces = map[string]string{
+	"dns":  "6ba7b810-9dad-11d1-80b4-00c04fd430c8",
+	"url":  "6ba7b811-9dad-11d1-80b4-00c04fd430c8",
+	"oid":  "6ba7b812-9dad-11d1-80b4-00c04fd430c8",
+	"x500": "6ba7b814-9dad-11d1-80b4-00c04fd430c8",
+}
+
+// cryptoResolvers returns the resolvers of tags hashing values. All of them
+// are deterministic, so that the output is stable between runs.
+func (i *CustomTagProcessor) cryptoResolvers() map[string]func(*yaml.Node) (*yaml.Node, error) {
+	return map[string]func(*yaml.Node) (*yaml.Node, error){
+		"!sha256": func(node *yaml.Node) (*yaml.Node, error) {
+			s, err := i.resolveStringArg(node)
+			if err != nil {
+				return nil, err
+			}
+			checksum := sha256.Sum256([]byte(s))
+			return computationScalar(node, hex.EncodeToString(checksum[:])), nil
+		},
+		"!md5": func(node *yaml.Node) (*yaml.Node, error) {
+			s, err := i.resolveStringArg(node)
+			if err != nil {
+				return nil, err
+			}
+			checksum := md5.Sum([]byte(s))
+			return computationScalar(node, hex.EncodeToString(checksum[:])), nil
+		},
+		// !uuid5 [namespace, name], where namespace is a UUID or one of
+		// `dns`, `url`, `oid` and `x500`
+		"!uuid5": func(node *yaml.Node) (*yaml.Node, error) {
+			args, err := i.resolveValueArgs(node, 2)
+			if err != nil {
+				return nil, err
+			}
+			if len(args) != 2 || args[0].Kind != yaml.ScalarNode || args[1].Kind != yaml.ScalarNode {
+				return nil, fmt.Errorf("line %d: !uuid5 requires a namespace and a name", node.Line)
+			}
+			uuid, err := UUID5(args[0].Value, args[1].Value)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: !uuid5: %s", node.Line, err)
+			}
+			return computationScalar(node, uuid), nil
+		},
+		// !bcrypt [password, seed, cost], where the salt is derived from
+		// the seed and cost defaults to 10
+		"!bcrypt": func(node *yaml.Node) (*yaml.Node, error) {
+			args, err := i.resolveValueArgs(node, 2)
+			if err != nil {
+				return nil, err
+			}
+			strs, err := computationStrings(node, args)
+			if err != nil {
+				return nil, err
+			}
+			if len(args) > 3 || len(strs) != len(args) {
+				return nil, fmt.Errorf("line %d: !bcrypt requires a password, a seed and an optional cost", node.Line)
+			}
+			cost := 10
+			if len(strs) == 3 {
+				cost, err = strconv.Atoi(strs[2])
+				if err != nil {
+					return nil, fmt.Errorf("line %d: !bcrypt: invalid cost '%s'", node.Line, strs[2])
+				}
+			}
+			hash, err := Bcrypt(strs[0], strs[1], cost)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: !bcrypt: %s", node.Line, err)
+			}
+			return computationScalar(node, hash), nil
+		},
+	}
+}
+
+// UUID5 returns the name based UUID (version 5) of name in namespace.
+func UUID5(namespace, name string) (string, error) {
+	if ns, ok := uuidNamespaces[strings.ToLower(namespace)]; ok {
+		namespace = ns
+	}
+	ns, err := hex.DecodeString(strings.ReplaceAll(namespace, "-", ""))
+	if err != nil || len(ns) != 16 {
+		return "", fmt.Errorf("invalid namespace '%s'", namespace)
+	}
+	h := sha1.New()
+	h.Write(ns)
+	h.Write([]byte(name))
+	u := h.Sum(nil)[:16]
+	u[6] = u[6]&0x0f | 0x50
+	u[8] = u[8]&0x3f | 0x80
+	s := hex.EncodeToString(u)
+	return s[0:8] + "-" + s[8:12] + "-" + s[12:16] + "-" + s[16:20] + "-" + s[20:], nil
+}
+
+var bcryptEncoding = base64.NewEncoding("./ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789").WithPadding(base64.NoPadding)
+
+// bcryptMaxCost limits the cost of hashes computed during plans, as each
+// step doubles the time of the key schedule.
+const bcryptMaxCost = 16
+
+// Bcrypt returns the bcrypt hash of password, where the salt is derived
+// from seed instead of being random.
+func Bcrypt(password, seed string, cost int) (string, error) {
+	if cost < 4 || cost > bcryptMaxCost {
+		return "", fmt.Errorf("cost %d is outside of the allowed range (4-%d)", cost, bcryptMaxCost)
+	}
+	if len(password) > 72 {
+		return "", fmt.Errorf("password is longer than 72 bytes")
+	}
+	seedHash := sha256.Sum256([]byte(seed))
+	salt := seedHash[:16]
+
+	// the key includes the trailing NUL character like C implementations
+	key := append([]byte(password), 0)
+	c, err := blowfish.NewSaltedCipher(key, salt)
+	if err != nil {
+		return "", err
+	}
+	for r := uint64(0); r < 1<<uint(cost); r++ {
+		blowfish.ExpandKey(key, c)
+		blowfish.ExpandKey(salt, c)
+	}
+	data := []byte("OrpheanBeholderScryDoubt")
+	for j := 0; j < 24; j += 8 {
+		for k := 0; k < 64; k++ {
+			c.Encrypt(data[j:j+8], data[j:j+8])
+		}
+	}
+	// only 23 of the 24 encrypted bytes are encoded like C implementations
+	return fmt.Sprintf("$2a$%02d$%s%s", cost, bcryptEncoding.EncodeToString(salt), bcryptEncoding.EncodeToString(data[:23])), nil
+}
@@ -0,0 +1,108 @@
+package provider
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestYamlUnmarshalCrypto(t *testing.T) {
+	input := `
+sha256: !sha256 abc
+md5: !md5 abc
+nested: !sha256 [!join ["", a, bc]]
+uuid5: !uuid5 [dns, python.org]
+uuid5_namespace: !uuid5 [6ba7b811-9dad-11d1-80b4-00c04fd430c8, "http://python.org/"]
+`
+	result := map[string]interface{}{
+		"sha256":          "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad",
+		"md5":             "900150983cd24fb0d6963f7d28e17f72",
+		"nested":          "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad",
+		"uuid5":           "886313e1-3b8a-5372-9b90-0c9aee199e5d",
+		"uuid5_namespace": "4c565f0d-3f5a-5890-b41b-20cf47701c5e",
+	}
+
+	var data map[string]interface{}
+	err := YamlUnmarshal([]byte(input), &data)
+	if err != nil {
+		t.Fatalf("Error reading YAML string: %s", err)
+	}
+	if !reflect.DeepEqual(data, result) {
+		t.Fatalf("Error matching data and result: %#v vs %#v", data, result)
+	}
+
+	cases := []struct {
+		input string
+		err   string
+	}{
+		{
+			input: "elem1: !uuid5 [invalid, name]\n",
+			err:   "line 1: !uuid5: invalid namespace 'invalid'",
+		},
+		{
+			input: "elem1: !uuid5 [dns, name, other]\n",
+			err:   "line 1: !uuid5 requires a namespace and a name",
+		},
+		{
+			input: "elem1: !bcrypt [password]\n",
+			err:   "line 1: !bcrypt: too few arguments",
+		},
+		{
+			input: "elem1: !bcrypt [password, seed, 3]\n",
+			err:   "line 1: !bcrypt: cost 3 is outside of the allowed range",
+		},
+		{
+			input: "elem1: !bcrypt [password, seed, 17]\n",
+			err:   "line 1: !bcrypt: cost 17 is outside of the allowed range (4-16)",
+		},
+		{
+			input: "a: x\nelem1: !sha256 [!ref a]\n",
+			err:   "line 2: !sha256 does not support references as arguments",
+		},
+		{
+			input: "a: x\nelem1: !md5 [!ref a]\n",
+			err:   "line 2: !md5 does not support references as arguments",
+		},
+		{
+			input: "a: x\nelem1: !uuid5 [dns, !ref a]\n",
+			err:   "line 2: !uuid5 does not support references as arguments",
+		},
+		{
+			input: "a: x\nelem1: !bcrypt [!ref a, seed]\n",
+			err:   "line 2: !bcrypt does not support references as arguments",
+		},
+	}
+
+	for _, c := range cases {
+		var data map[string]interface{}
+		err := YamlUnmarshal([]byte(c.input), &data)
+		if err == nil || !strings.Contains(err.Error(), c.err) {
+			t.Fatalf("Error matching error: %v vs %s", err, c.err)
+		}
+	}
+}
+
+func TestBcrypt(t *testing.T) {
+	var data map[string]interface{}
+	err := YamlUnmarshal([]byte("hash1: !bcrypt [secret, seed1, 4]\nhash2: !bcrypt [secret, seed1, 4]\nhash3: !bcrypt [secret, seed2, 4]\n"), &data)
+	if err != nil {
+		t.Fatalf("Error reading YAML string: %s", err)
+	}
+	hash := data["hash1"].(string)
+	if !strings.HasPrefix(hash, "$2a$04$") || len(hash) != 60 {
+		t.Fatalf("Error matching hash format: %s", hash)
+	}
+	if hash != data["hash2"] || hash == data["hash3"] {
+		t.Fatalf("Error matching hashes: %v", data)
+	}
+	err = bcrypt.CompareHashAndPassword([]byte(hash), []byte("secret"))
+	if err != nil {
+		t.Fatalf("Error verifying hash: %s", err)
+	}
+	err = bcrypt.CompareHashAndPassword([]byte(hash), []byte("other"))
+	if err == nil {
+		t.Fatalf("Error verifying hash with wrong password")
+	}
+}
@@ -0,0 +1,53 @@
+package provider
+
+import (
+	"fmt"
+	"regexp"
+
+	"gopkg.in/yaml.v3"
+)
+
+// CustomTag is a YAML tag defined in the provider configuration, which
+// transforms scalar values. The lookup table is applied first, followed by
+// the regular expression replacement and the prefix and suffix.
+type CustomTag struct {
+	Name        string
+	Lookup      map[string]string
+	Regex       *regexp.Regexp
+	Replacement string
+	Prefix      string
+	Suffix      string
+}
+
+// Tag returns the YAML tag, e.g. `!site_prefix`.
+func (t CustomTag) Tag() string {
+	return "!" + t.Name
+}
+
+func (t CustomTag) Resolve(node *yaml.Node) (*yaml.Node, error) {
+	if node.Kind != yaml.ScalarNode {
+		return nil, fmt.Errorf("%s on a non-scalar node", t.Tag())
+	}
+	value := node.Value
+	if t.Lookup != nil {
+		v, ok := t.Lookup[value]
+		if !ok {
+			return nil, fmt.Errorf("%s: value '%s' not found in lookup table", t.Tag(), value)
+		}
+		value = v
+	}
+	if t.Regex != nil {
+		value = t.Regex.ReplaceAllString(value, t.Replacement)
+	}
+	node.Value = t.Prefix + value + t.Suffix
+	return node, nil
+}
+
+// isBuiltinTag returns true for tags resolved by the provider itself.
+func isBuiltinTag(tag string) bool {
+	if tag == "!env" || tag == "!ref" || tag == "!if" || tag == "!unless" {
+		return true
+	}
+	_, ok := newCustomTagProcessor(nil, YamlOptions{}).resolvers[tag]
+	return ok
+}
@@ -0,0 +1,51 @@
+package provider
+
+import (
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestCustomTag(t *testing.T) {
+	tags := []CustomTag{
+		{Name: "test_site", Lookup: map[string]string{"zurich": "ZRH", "geneva": "GVA"}, Prefix: "site-"},
+		{Name: "test_regex", Regex: regexp.MustCompile(`^(\w+)\.example\.com$`), Replacement: "$1"},
+		{Name: "test_suffix", Suffix: ".example.com"},
+	}
+
+	input := `
+site: !test_site zurich
+host: !test_regex host1.example.com
+fqdn: !test_suffix host1
+`
+	result := map[string]interface{}{
+		"site": "site-ZRH",
+		"host": "host1",
+		"fqdn": "host1.example.com",
+	}
+
+	var data map[string]interface{}
+	err := YamlUnmarshalWithOptions([]byte(input), &data, YamlOptions{CustomTags: tags})
+	if err != nil {
+		t.Fatalf("Error reading YAML string: %s", err)
+	}
+	if !reflect.DeepEqual(data, result) {
+		t.Fatalf("Error matching data and result: %#v vs %#v", data, result)
+	}
+
+	err = YamlUnmarshalWithOptions([]byte("site: !test_site bern\n"), &data, YamlOptions{CustomTags: tags})
+	if err == nil || !strings.Contains(err.Error(), "!test_site: value 'bern' not found in lookup table") {
+		t.Fatalf("Error matching error: %v", err)
+	}
+
+	// custom tags are only resolved if passed in the options
+	err = YamlUnmarshalWithOptions([]byte("site: !test_site zurich\n"), &data, YamlOptions{Strict: true})
+	if err == nil || !strings.Contains(err.Error(), "unknown tag !test_site") {
+		t.Fatalf("Error matching error: %v", err)
+	}
+
+	if !isBuiltinTag("!env") || !isBuiltinTag("!join") || isBuiltinTag("!test_site") {
+		t.Fatalf("Error matching builtin tags")
+	}
+}
@@ -2,61 +2,133 @@ package provider
 
 import (
 	"context"
-	"crypto/sha1"
-	"encoding/hex"
 	"fmt"
 	"reflect"
 
+	"github.com/hashicorp/terraform-plugin-framework/attr"
 	"github.com/hashicorp/terraform-plugin-framework/datasource"
 	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
 	"github.com/hashicorp/terraform-plugin-framework/types"
 	"gopkg.in/yaml.v3"
 )
 
 var _ datasource.DataSource = (*yamlMergeDataSource)(nil)
+var _ datasource.DataSourceWithConfigure = (*yamlMergeDataSource)(nil)
 
 func NewYamlMergeDataSource() datasource.DataSource {
 	return &yamlMergeDataSource{}
 }
 
-type yamlMergeDataSource struct{}
+type yamlMergeDataSource struct {
+	data *utilsProviderData
+}
 
 func (d *yamlMergeDataSource) Metadata(_ context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
 	resp.TypeName = req.ProviderTypeName + "_yaml_merge"
 }
 
+func (d *yamlMergeDataSource) Configure(_ context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+	d.data = req.ProviderData.(*utilsProviderData)
+}
+
 func (d *yamlMergeDataSource) Schema(ctx context.Context, req datasource.SchemaRequest, resp *datasource.SchemaResponse) {
 	resp.Schema = schema.Schema{
 		// This description is used by the documentation generator and the language server.
-		MarkdownDescription: "Merge a list of YAML strings into a single YAML string, where maps are deep merged and list entries are compared against existing list entries and if all primitive values match, the entries are deep merged. YAML `!env` tags can be used to resolve values from environment variables.",
-
-		Attributes: map[string]schema.Attribute{
-			"id": schema.StringAttribute{
-				Description: "Hexadecimal encoding of the checksum of the output.",
-				Computed:    true,
-			},
-			"input": schema.ListAttribute{
-				Description: "A list of YAML strings that is merged into the `output` attribute.",
-				ElementType: types.StringType,
-				Required:    true,
-			},
-			"output": schema.StringAttribute{
-				Description: "The merged output.",
-				Computed:    true,
-			},
-			"merge_list_items": schema.BoolAttribute{
-				Description: "Merge list entries if all primitive values match. Default value is `true`.",
-				Optional:    true,
-			},
-		},
+		MarkdownDescription: "Merge a list of YAML strings into a single YAML string, where maps are deep merged and list entries are compared against existing list entries and if all primitive values match, the entries are deep merged. YAML `!env` tags can be used to resolve values from environment variables, YAML `!var` tags to resolve values from `variables`, YAML `!file`, `!base64`, `!base64file` and `!filehash` tags to embed file content and YAML `!ref` tags to reference values of the merged output, e.g. `!ref path.to.value`. YAML `!if` and `!unless` tags with a `condition` and a `value` key conditionally include map values and list items, where conditions can use environment variables (`env.NAME`) and values of previous inputs (`ref:path.to.value`). YAML `!concat`, `!join`, `!split`, `!upper`, `!lower` and `!format` tags transform values and can be nested, e.g. `!join [\",\", !split [\";\", !env HOSTS]]`. Their arguments cannot be `!ref` values, which are resolved after merging. YAML `!sha256`, `!md5`, `!uuid5` (namespace and name) and `!bcrypt` (password, seed and optional cost) tags compute deterministic hashes and do not accept `!ref` values either. If inputs are unknown during plan, e.g. because they reference attributes of resources which are not created yet, Terraform defers the read until apply and `output` is unknown during plan.",
+
+		Attributes: yamlMergeAttributes(dataSourceAttributes),
+	}
+}
+
+// dataSourceAttributes builds data source attributes.
+var dataSourceAttributes = attributeBuilder[schema.Attribute]{
+	String: func(s attributeSpec) schema.Attribute {
+		return schema.StringAttribute{Description: s.Description, Required: s.Required, Optional: s.Optional, Computed: s.Computed}
+	},
+	Bool: func(s attributeSpec) schema.Attribute {
+		return schema.BoolAttribute{Description: s.Description, Required: s.Required, Optional: s.Optional, Computed: s.Computed}
+	},
+	Int64: func(s attributeSpec) schema.Attribute {
+		return schema.Int64Attribute{Description: s.Description, Required: s.Required, Optional: s.Optional, Computed: s.Computed}
+	},
+	List: func(s attributeSpec, elementType attr.Type) schema.Attribute {
+		return schema.ListAttribute{Description: s.Description, ElementType: elementType, Required: s.Required, Optional: s.Optional, Computed: s.Computed}
+	},
+	Map: func(s attributeSpec, elementType attr.Type) schema.Attribute {
+		return schema.MapAttribute{Description: s.Description, ElementType: elementType, Required: s.Required, Optional: s.Optional, Computed: s.Computed}
+	},
+	Dynamic: func(s attributeSpec) schema.Attribute {
+		return schema.DynamicAttribute{Description: s.Description, Required: s.Required, Optional: s.Optional, Computed: s.Computed}
+	},
+}
+
+// yamlMergeAttributes returns the attributes of the `utils_yaml_merge` data
+// source built by b, so that the `utils_yaml_file` resource and the
+// `utils_yaml_merge` ephemeral resource accept the same inputs and options.
+func yamlMergeAttributes[A any](b attributeBuilder[A]) map[string]A {
+	return map[string]A{
+		"id":                 b.String(attributeSpec{Description: "Hexadecimal encoding of the SHA-256 checksum of the canonical form of the output, which is the same for semantically equal outputs regardless of their formatting.", Computed: true}),
+		"input":              b.List(attributeSpec{Description: "A list of YAML strings that is merged into the `output` attribute.", Required: true}, types.StringType),
+		"input_format":       b.List(attributeSpec{Description: "A list of formats of the inputs, one per `input`: `yaml`, `json`, `toml` or `hcl`. A single format applies to all inputs. HCL inputs only support attributes and YAML tags are only supported in YAML inputs. Default value is `yaml`.", Optional: true}, types.StringType),
+		"output":             b.String(attributeSpec{Description: "The merged output.", Computed: true}),
+		"merge_list_items":   b.Bool(attributeSpec{Description: "Merge list entries if all primitive values match. Default value is `true`.", Optional: true}),
+		"strategic_merge":    b.Bool(attributeSpec{Description: "Honour strategic merge patch directives: `$patch` (`replace`, `delete` or `merge`), `$retainKeys` and `$setElementOrder/<list>`. Directive keys are removed from the output. Default value is `false`.", Optional: true}),
+		"merge_key":          b.String(attributeSpec{Description: "Key used to match list entries. If set, list entries with the same value for this key are deep merged.", Optional: true}),
+		"shared_anchors":     b.Bool(attributeSpec{Description: "Allow inputs to reference YAML anchors defined in previous inputs. Inputs referencing shared anchors must have a block mapping at the root. Default value is `false`.", Optional: true}),
+		"interpolate":        b.Bool(attributeSpec{Description: "Interpolate `${env.NAME}` environment variables and `${ref:path.to.value}` references to values of the merged output in string values. Use `$${` for a literal `${`. Default value is `false`.", Optional: true}),
+		"template_vars":      b.Dynamic(attributeSpec{Description: "Variables used to render each input as Go template before it is parsed. Besides the builtin template functions, `default`, `indent`, `join`, `lower`, `quote`, `replace`, `split`, `toYaml`, `trim` and `upper` are available. Inputs are only rendered if this attribute is set.", Optional: true}),
+		"base_dir":           b.String(attributeSpec{Description: "Base directory of files referenced by `!file`, `!base64file` and `!filehash` tags. Files outside of the base directory cannot be referenced. Defaults to the current working directory.", Optional: true}),
+		"variables":          b.Map(attributeSpec{Description: "Variables resolved by YAML `!var` tags.", Optional: true}, types.StringType),
+		"env_from_variables": b.Bool(attributeSpec{Description: "Resolve YAML `!env` tags, `${env.NAME}` interpolations and `env.NAME` operands of conditions from `variables` instead of `env_files` and the environment of the provider, so that the output only depends on the configuration. Default value is `false`.", Optional: true}),
+		"env_files":          b.List(attributeSpec{Description: "A list of `.env` or Java `.properties` files with variables resolved by YAML `!env` tags, which take precedence over the `env_files` of the provider and the environment of the provider. Variables of later files override variables of earlier files.", Optional: true}, types.StringType),
+		"strict":             b.Bool(attributeSpec{Description: "Fail on unknown YAML tags, e.g. `!evn`, and duplicate keys. Default value is `false`.", Optional: true}),
+		"strict_booleans":    b.Bool(attributeSpec{Description: "Fail on unquoted YAML 1.1 booleans like `yes`, `no`, `on` and `off`, which are strings in YAML 1.2. Default value is `false`.", Optional: true}),
+		"output_indent":      b.Int64(attributeSpec{Description: "Number of spaces used for indentation of the output, between `2` and `9`. Default value is `4`.", Optional: true}),
+		"sort_keys":          b.String(attributeSpec{Description: "Order of keys in the output: `none` for the natural order of the YAML encoder, `alpha` for alphabetical order or `first_seen` for the order in which keys first appear in the inputs. Default value is `none`.", Optional: true}),
+		"string_style":       b.String(attributeSpec{Description: "Style of string values in the output: `auto` to keep the original style, `double_quoted`, `single_quoted` or `literal` for block literals of multi-line strings. Default value is `auto`.", Optional: true}),
+		"canonical":          b.Bool(attributeSpec{Description: "Write the output in its canonical form, where keys are sorted alphabetically, scalars are normalized and the indentation is 2 spaces. The `output_indent`, `sort_keys` and `string_style` attributes are ignored. Default value is `false`.", Optional: true}),
+		"defaults":           b.String(attributeSpec{Description: "A YAML string with default values, which are added to the merged output where a key is missing. Maps are applied to every list item at the same path, values from `input` are never overridden.", Optional: true}),
+		"schema":             b.String(attributeSpec{Description: "A Yamale schema used to validate the merged output. Additional YAML documents in the schema define includes.", Optional: true}),
+		"validate_inputs":    b.Bool(attributeSpec{Description: "Validate each input against `schema` before merging. Missing required fields are not reported for individual inputs. Default value is `false`.", Optional: true}),
 	}
 }
 
 type YamlMerge struct {
-	Id             types.String `tfsdk:"id"`
-	Input          []string     `tfsdk:"input"`
-	Output         types.String `tfsdk:"output"`
-	MergeListItems types.Bool   `tfsdk:"merge_list_items"`
+	Id types.String `tfsdk:"id"`
+	YamlMergeBase
+}
+
+// YamlMergeBase are the attributes shared by the `utils_yaml_merge` data
+// source and ephemeral resource and the `utils_yaml_file` resource. The
+// ephemeral resource has no id.
+type YamlMergeBase struct {
+	Input            []string          `tfsdk:"input"`
+	InputFormat      []string          `tfsdk:"input_format"`
+	Output           types.String      `tfsdk:"output"`
+	MergeListItems   types.Bool        `tfsdk:"merge_list_items"`
+	StrategicMerge   types.Bool        `tfsdk:"strategic_merge"`
+	MergeKey         types.String      `tfsdk:"merge_key"`
+	SharedAnchors    types.Bool        `tfsdk:"shared_anchors"`
+	Interpolate      types.Bool        `tfsdk:"interpolate"`
+	TemplateVars     types.Dynamic     `tfsdk:"template_vars"`
+	BaseDir          types.String      `tfsdk:"base_dir"`
+	Variables        map[string]string `tfsdk:"variables"`
+	EnvFromVariables types.Bool        `tfsdk:"env_from_variables"`
+	EnvFiles         []string          `tfsdk:"env_files"`
+	Strict           types.Bool        `tfsdk:"strict"`
+	StrictBooleans   types.Bool        `tfsdk:"strict_booleans"`
+	OutputIndent     types.Int64       `tfsdk:"output_indent"`
+	SortKeys         types.String      `tfsdk:"sort_keys"`
+	StringStyle      types.String      `tfsdk:"string_style"`
+	Canonical        types.Bool        `tfsdk:"canonical"`
+	Defaults         types.String      `tfsdk:"defaults"`
+	Schema           types.String      `tfsdk:"schema"`
+	ValidateInputs   types.Bool        `tfsdk:"validate_inputs"`
 }
 
 func (d *yamlMergeDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
@@ -69,51 +141,290 @@ func (d *yamlMergeDataSource) Read(ctx context.Context, req datasource.ReadReque
 		return
 	}
 
+	hash, diags := config.read(ctx, d.data)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	config.Id = types.StringValue(hash)
+
+	diags = resp.State.Set(ctx, &config)
+	resp.Diagnostics.Append(diags...)
+}
+
+// read merges the inputs, sets the output and returns its id. Terraform
+// defers reads with unknown inputs until apply, e.g. if inputs reference
+// attributes of resources which are not created yet, so all inputs are known.
+func (config *YamlMergeBase) read(ctx context.Context, data *utilsProviderData) (string, diag.Diagnostics) {
+	yamlOptions, marshalOptions, yamaleSchema, diags := config.options(ctx, data)
+	if diags.HasError() {
+		return "", diags
+	}
+
+	output, hash, mergeDiags := config.merge(yamlOptions, marshalOptions, yamaleSchema)
+	diags.Append(mergeDiags...)
+	if diags.HasError() {
+		return "", diags
+	}
+	config.Output = types.StringValue(output)
+	return hash, diags
+}
+
+// options returns the options used to read the inputs and to write the
+// output of the merge.
+func (config *YamlMergeBase) options(ctx context.Context, data *utilsProviderData) (YamlOptions, YamlMarshalOptions, *YamaleSchema, diag.Diagnostics) {
+	var diags diag.Diagnostics
+
 	if config.MergeListItems.IsUnknown() || config.MergeListItems.IsNull() {
 		config.MergeListItems = types.BoolValue(true)
 	}
 
+	marshalOptions := YamlMarshalOptions{
+		Indent:      4,
+		SortKeys:    "none",
+		StringStyle: "auto",
+	}
+	if !config.OutputIndent.IsNull() {
+		marshalOptions.Indent = int(config.OutputIndent.ValueInt64())
+		if marshalOptions.Indent < 2 || marshalOptions.Indent > 9 {
+			diags.AddAttributeError(
+				path.Root("output_indent"),
+				"Invalid output indent",
+				fmt.Sprintf("Invalid output indent %d, the indent must be between 2 and 9.", marshalOptions.Indent),
+			)
+		}
+	}
+	if !config.SortKeys.IsNull() {
+		marshalOptions.SortKeys = config.SortKeys.ValueString()
+		if marshalOptions.SortKeys != "none" && marshalOptions.SortKeys != "alpha" && marshalOptions.SortKeys != "first_seen" {
+			diags.AddAttributeError(
+				path.Root("sort_keys"),
+				"Invalid key order",
+				fmt.Sprintf("Invalid key order '%s', must be one of `none`, `alpha` or `first_seen`.", marshalOptions.SortKeys),
+			)
+		}
+	}
+	if !config.StringStyle.IsNull() {
+		marshalOptions.StringStyle = config.StringStyle.ValueString()
+		if marshalOptions.StringStyle != "auto" && marshalOptions.StringStyle != "double_quoted" && marshalOptions.StringStyle != "single_quoted" && marshalOptions.StringStyle != "literal" {
+			diags.AddAttributeError(
+				path.Root("string_style"),
+				"Invalid string style",
+				fmt.Sprintf("Invalid string style '%s', must be one of `auto`, `double_quoted`, `single_quoted` or `literal`.", marshalOptions.StringStyle),
+			)
+		}
+	}
+	if len(config.InputFormat) > 1 && len(config.InputFormat) != len(config.Input) {
+		diags.AddAttributeError(
+			path.Root("input_format"),
+			"Invalid input formats",
+			fmt.Sprintf("Invalid input formats, expected 1 or %d formats, got %d.", len(config.Input), len(config.InputFormat)),
+		)
+	}
+	for i, format := range config.InputFormat {
+		if !isInputFormat(format) {
+			diags.AddAttributeError(
+				path.Root("input_format").AtListIndex(i),
+				"Invalid input format",
+				fmt.Sprintf("Invalid input format '%s', must be one of `yaml`, `json`, `toml` or `hcl`.", format),
+			)
+		}
+	}
+	if diags.HasError() {
+		return YamlOptions{}, YamlMarshalOptions{}, nil, diags
+	}
+
+	var yamaleSchema *YamaleSchema
+	if !config.Schema.IsNull() {
+		var err error
+		yamaleSchema, err = ParseYamaleSchema([]byte(config.Schema.ValueString()))
+		if err != nil {
+			diags.AddError(
+				"Error reading schema",
+				fmt.Sprintf("Error reading schema: %s", err),
+			)
+			return YamlOptions{}, YamlMarshalOptions{}, nil, diags
+		}
+	}
+
+	yamlOptions := YamlOptions{
+		Variables:        config.Variables,
+		EnvFromVariables: config.EnvFromVariables.ValueBool(),
+		Interpolate:      config.Interpolate.ValueBool(),
+		BaseDir:          config.BaseDir.ValueString(),
+		Strict:           config.Strict.ValueBool(),
+		StrictBooleans:   config.StrictBooleans.ValueBool(),
+		ScalarStyles:     NewScalarStyles(),
+	}
+	var providerEnv map[string]string
+	if data != nil {
+		yamlOptions.CustomTags = data.CustomTags
+		providerEnv = data.Env
+	}
+	vars, err := LoadEnvFiles(config.EnvFiles)
+	if err != nil {
+		diags.AddError(
+			"Error reading env files",
+			fmt.Sprintf("Error reading env files: %s", err),
+		)
+		return YamlOptions{}, YamlMarshalOptions{}, nil, diags
+	}
+	yamlOptions.Env = MergeEnv(providerEnv, vars)
+	if !config.TemplateVars.IsNull() {
+		vars, err := GoValue(ctx, config.TemplateVars)
+		if err != nil {
+			diags.AddError(
+				"Error reading template variables",
+				fmt.Sprintf("Error reading template variables: %s", err),
+			)
+			return YamlOptions{}, YamlMarshalOptions{}, nil, diags
+		}
+		if vars == nil {
+			vars = map[string]interface{}{}
+		}
+		yamlOptions.TemplateVars = vars
+	}
+	if config.SharedAnchors.ValueBool() {
+		yamlOptions.SharedAnchors = map[string]*yaml.Node{}
+	}
+
+	return yamlOptions, marshalOptions, yamaleSchema, diags
+}
+
+// merge merges the inputs and returns the output and its id.
+func (config *YamlMergeBase) merge(yamlOptions YamlOptions, marshalOptions YamlMarshalOptions, yamaleSchema *YamaleSchema) (string, string, diag.Diagnostics) {
+	var diags diag.Diagnostics
+
 	merged := map[interface{}]interface{}{}
 	vMerged := reflect.ValueOf(merged)
-	for _, input := range config.Input {
+	// conditions reference values of previous inputs
+	yamlOptions.Refs = merged
+	for i, input := range config.Input {
 		var data map[interface{}]interface{}
 		b := []byte(input)
 
-		err := YamlUnmarshal(b, &data)
+		format := "yaml"
+		if len(config.InputFormat) == 1 {
+			format = config.InputFormat[0]
+		} else if len(config.InputFormat) > 1 {
+			format = config.InputFormat[i]
+		}
+
+		yamlOptions.Name = fmt.Sprintf("input[%d]", i)
+		err := UnmarshalWithFormat(format, b, &data, yamlOptions)
 		if err != nil {
-			resp.Diagnostics.AddError(
+			diags.AddError(
 				"Error reading YAML string",
-				fmt.Sprintf("Error reading YAML string: %s", err),
+				fmt.Sprintf("Error reading YAML string: %s: %s", yamlOptions.Name, err),
 			)
-			return
+			return "", "", diags
+		}
+
+		if yamaleSchema != nil && config.ValidateInputs.ValueBool() {
+			for _, e := range yamaleSchema.Validate(data, true) {
+				diags.AddError(
+					"Error validating YAML",
+					fmt.Sprintf("Error validating YAML: input[%d]: %s", i, e),
+				)
+			}
 		}
 
 		vData := reflect.ValueOf(data)
 
-		err = MergeMaps(vMerged, vData, config.MergeListItems.ValueBool())
+		err = MergeMapsWithOptions(vMerged, vData, MergeOptions{
+			MergeListItems: config.MergeListItems.ValueBool(),
+			Strategic:      config.StrategicMerge.ValueBool(),
+			MergeKey:       config.MergeKey.ValueString(),
+		})
 		if err != nil {
-			resp.Diagnostics.AddError(
+			diags.AddError(
 				"Error merging YAML",
 				fmt.Sprintf("Error merging YAML: %s", err),
 			)
-			return
+			return "", "", diags
 		}
 	}
 
-	output, err := yaml.Marshal(merged)
+	if diags.HasError() {
+		return "", "", diags
+	}
+
+	if !config.Defaults.IsNull() {
+		var defaults map[interface{}]interface{}
+		err := YamlUnmarshalWithOptions([]byte(config.Defaults.ValueString()), &defaults, YamlOptions{
+			Interpolate:      yamlOptions.Interpolate,
+			BaseDir:          yamlOptions.BaseDir,
+			Strict:           yamlOptions.Strict,
+			StrictBooleans:   yamlOptions.StrictBooleans,
+			ScalarStyles:     yamlOptions.ScalarStyles,
+			Env:              yamlOptions.Env,
+			Variables:        yamlOptions.Variables,
+			EnvFromVariables: yamlOptions.EnvFromVariables,
+			CustomTags:       yamlOptions.CustomTags,
+			Name:             "defaults",
+		})
+		if err != nil {
+			diags.AddError(
+				"Error reading defaults",
+				fmt.Sprintf("Error reading defaults: %s", err),
+			)
+			return "", "", diags
+		}
+		if defaults != nil {
+			err = ApplyDefaults(vMerged, reflect.ValueOf(defaults))
+			if err != nil {
+				diags.AddError(
+					"Error applying defaults",
+					fmt.Sprintf("Error applying defaults: %s", err),
+				)
+				return "", "", diags
+			}
+		}
+	}
+
+	_, err := ResolveRefs(merged)
 	if err != nil {
-		resp.Diagnostics.AddError(
-			"Error converting result to YAML",
-			fmt.Sprintf("Error converting result to YAML: %s", err),
+		diags.AddError(
+			"Error resolving references",
+			fmt.Sprintf("Error resolving references: %s", err),
 		)
-		return
+		return "", "", diags
 	}
 
-	config.Output = types.StringValue(string(output))
+	if yamaleSchema != nil {
+		for _, e := range yamaleSchema.Validate(merged, false) {
+			diags.AddError(
+				"Error validating YAML",
+				fmt.Sprintf("Error validating YAML: output: %s", e),
+			)
+		}
+		if diags.HasError() {
+			return "", "", diags
+		}
+	}
 
-	checksum := sha1.Sum(output)
-	config.Id = types.StringValue(hex.EncodeToString(checksum[:]))
+	var output []byte
+	if config.Canonical.ValueBool() {
+		output, err = CanonicalYaml(merged)
+	} else {
+		marshalOptions.Styles = yamlOptions.ScalarStyles
+		output, err = YamlMarshalWithOptions(merged, marshalOptions)
+	}
+	if err != nil {
+		diags.AddError(
+			"Error converting result to YAML",
+			fmt.Sprintf("Error converting result to YAML: %s", err),
+		)
+		return "", "", diags
+	}
 
-	diags = resp.State.Set(ctx, &config)
-	resp.Diagnostics.Append(diags...)
+	hash, err := CanonicalHash(merged)
+	if err != nil {
+		diags.AddError(
+			"Error hashing YAML",
+			fmt.Sprintf("Error hashing YAML: %s", err),
+		)
+		return "", "", diags
+	}
+	return string(output), hash, diags
 }
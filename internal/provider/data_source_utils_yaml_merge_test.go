@@ -1,11 +1,18 @@
 package provider
 
 import (
+	"context"
 	"fmt"
-	"os"
+	"regexp"
+	"sort"
+	"strings"
 	"testing"
 
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
 	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+	"github.com/hashicorp/terraform-plugin-testing/tfversion"
 )
 
 func TestAccDataSourceUtilsYamlMerge(t *testing.T) {
@@ -23,10 +30,551 @@ func TestAccDataSourceUtilsYamlMerge(t *testing.T) {
 	})
 }
 
-func testAccDataSourceUtilsYamlMerge_config(yaml1, yaml2 string, envs map[string]string) string {
-	for k, v := range envs {
-		os.Setenv(k, v)
+func TestAccDataSourceUtilsYamlMerge_schema(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccDataSourceUtilsYamlMerge_schemaConfig(schema_inputYaml, "name: str()\\nvlan: int(max=4094)\\n"),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("data.utils_yaml_merge.test", "output", schema_inputYaml),
+				),
+			},
+			{
+				Config:      testAccDataSourceUtilsYamlMerge_schemaConfig(schema_inputYaml, "name: str()\\nvlan: int(max=100)\\n"),
+				ExpectError: regexp.MustCompile(`input\[0\]: vlan: 1000 is greater than 100`),
+			},
+		},
+	})
+}
+
+func testAccDataSourceUtilsYamlMerge_schemaConfig(yaml1, schema string) string {
+	return fmt.Sprintf(`
+	locals {
+		yaml1 = <<-EOT
+%sEOT
+	}
+
+	data "utils_yaml_merge" "test" {
+		input           = [local.yaml1]
+		schema          = "%s"
+		validate_inputs = true
+	}
+	`, yaml1, schema)
+}
+
+const schema_inputYaml = `name: abc
+vlan: 1000
+`
+
+func TestAccDataSourceUtilsYamlMerge_defaults(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: `
+				locals {
+					yaml1 = <<-EOT
+						tenants:
+						  - name: t1
+						    vrfs:
+						      - name: v1
+						      - name: v2
+						        enforced: false
+					EOT
+					defaults = <<-EOT
+						tenants:
+						  vrfs:
+						    enforced: true
+					EOT
+				}
+
+				data "utils_yaml_merge" "test" {
+					input    = [local.yaml1]
+					defaults = local.defaults
+				}
+				`,
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("data.utils_yaml_merge.test", "output", defaults_ouputYaml),
+				),
+			},
+		},
+	})
+}
+
+const defaults_ouputYaml = `tenants:
+    - name: t1
+      vrfs:
+        - enforced: true
+          name: v1
+        - enforced: false
+          name: v2
+`
+
+func TestAccDataSourceUtilsYamlMerge_strategicMerge(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: `
+				locals {
+					yaml1 = <<-EOT
+						root:
+						  child1: abc
+						list:
+						  - name: a1
+						    value: 1
+						  - name: a2
+					EOT
+					yaml2 = <<-EOT
+						root:
+						  $patch: replace
+						  child2: def
+						list:
+						  - name: a1
+						    value: 2
+						  - name: a2
+						    $patch: delete
+					EOT
+				}
+
+				data "utils_yaml_merge" "test" {
+					input           = [local.yaml1, local.yaml2]
+					strategic_merge = true
+					merge_key       = "name"
+				}
+				`,
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("data.utils_yaml_merge.test", "output", strategicMerge_ouputYaml),
+				),
+			},
+		},
+	})
+}
+
+const strategicMerge_ouputYaml = `list:
+    - name: a1
+      value: 2
+root:
+    child2: def
+`
+
+func TestAccDataSourceUtilsYamlMerge_sharedAnchors(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: `
+				locals {
+					yaml1 = <<-EOT
+						base: &base
+						  elem1: value1
+					EOT
+					yaml2 = <<-EOT
+						other:
+						  <<: *base
+						  elem2: value2
+					EOT
+				}
+
+				data "utils_yaml_merge" "test" {
+					input          = [local.yaml1, local.yaml2]
+					shared_anchors = true
+				}
+				`,
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("data.utils_yaml_merge.test", "output", sharedAnchors_ouputYaml),
+				),
+			},
+		},
+	})
+}
+
+const sharedAnchors_ouputYaml = `base:
+    elem1: value1
+other:
+    elem1: value1
+    elem2: value2
+`
+
+func TestAccDataSourceUtilsYamlMerge_ref(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: `
+				locals {
+					yaml1 = <<-EOT
+						vrf:
+						  tenant: !ref tenant.name
+					EOT
+					yaml2 = <<-EOT
+						tenant:
+						  name: tenant1
+					EOT
+				}
+
+				data "utils_yaml_merge" "test" {
+					input = [local.yaml1, local.yaml2]
+				}
+				`,
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("data.utils_yaml_merge.test", "output", ref_ouputYaml),
+				),
+			},
+			{
+				Config: `
+				data "utils_yaml_merge" "test" {
+					input = ["vrf: !ref tenant.name"]
+				}
+				`,
+				ExpectError: regexp.MustCompile(`path 'tenant' does not exist`),
+			},
+		},
+	})
+}
+
+const ref_ouputYaml = `tenant:
+    name: tenant1
+vrf:
+    tenant: tenant1
+`
+
+func TestAccDataSourceUtilsYamlMerge_interpolate(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: `
+				locals {
+					yaml1 = <<-EOT
+						vrf:
+						  name: $${ref:tenant.name}-vrf
+						  description: $$$${literal}
+					EOT
+					yaml2 = <<-EOT
+						tenant:
+						  name: tenant1
+					EOT
+				}
+
+				data "utils_yaml_merge" "test" {
+					input       = [local.yaml1, local.yaml2]
+					interpolate = true
+				}
+				`,
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("data.utils_yaml_merge.test", "output", interpolate_ouputYaml),
+				),
+			},
+			{
+				Config: `
+				data "utils_yaml_merge" "test" {
+					input       = ["vrf: value", "vrf: $${ref:tenant.name}"]
+					interpolate = true
+				}
+				`,
+				ExpectError: regexp.MustCompile(`input\[1\] line 1: !ref tenant.name`),
+			},
+		},
+	})
+}
+
+const interpolate_ouputYaml = `tenant:
+    name: tenant1
+vrf:
+    description: ${literal}
+    name: tenant1-vrf
+`
+
+func TestAccDataSourceUtilsYamlMerge_templateVars(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: `
+				locals {
+					yaml1 = <<-EOT
+						tenant: {{ .tenant }}
+						vrfs:
+						{{- range .vrfs }}
+						  - name: {{ . }}
+						{{- end }}
+					EOT
+				}
+
+				data "utils_yaml_merge" "test" {
+					input         = [local.yaml1]
+					template_vars = {
+						tenant = "tenant1"
+						vrfs   = ["vrf1", "vrf2"]
+					}
+				}
+				`,
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("data.utils_yaml_merge.test", "output", templateVars_ouputYaml),
+				),
+			},
+		},
+	})
+}
+
+const templateVars_ouputYaml = `tenant: tenant1
+vrfs:
+    - name: vrf1
+    - name: vrf2
+`
+
+func TestAccDataSourceUtilsYamlMerge_customTag(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: `
+				provider "utils" {
+					tag {
+						name   = "site_prefix"
+						lookup = { zurich = "ZRH" }
+						prefix = "site-"
+					}
+				}
+
+				data "utils_yaml_merge" "test" {
+					input = ["site: !site_prefix zurich"]
+				}
+				`,
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("data.utils_yaml_merge.test", "output", "site: site-ZRH\n"),
+				),
+			},
+			{
+				Config: `
+				provider "utils" {
+					tag {
+						name = "env"
+					}
+				}
+
+				data "utils_yaml_merge" "test" {
+					input = ["site: value"]
+				}
+				`,
+				ExpectError: regexp.MustCompile(`Invalid tag name`),
+			},
+		},
+	})
+}
+
+func TestAccDataSourceUtilsYamlMerge_outputFormat(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: `
+				data "utils_yaml_merge" "test" {
+					input         = ["tenant:\n  name: tenant1\n  description: \"line1\\nline2\\n\"\n"]
+					output_indent = 2
+					sort_keys     = "first_seen"
+					string_style  = "literal"
+				}
+				`,
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("data.utils_yaml_merge.test", "output", outputFormat_ouputYaml),
+				),
+			},
+			{
+				Config: `
+				data "utils_yaml_merge" "test" {
+					input     = ["tenant: tenant1"]
+					sort_keys = "random"
+				}
+				`,
+				ExpectError: regexp.MustCompile(`Invalid key order`),
+			},
+		},
+	})
+}
+
+const outputFormat_ouputYaml = `tenant:
+  name: tenant1
+  description: |
+    line1
+    line2
+`
+
+func TestAccDataSourceUtilsYamlMerge_canonical(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: `
+				data "utils_yaml_merge" "test1" {
+					input     = ["b: 'value'\na:\n    d: 0x10\n    c: \"1.50\"\n"]
+					canonical = true
+				}
+
+				data "utils_yaml_merge" "test2" {
+					input = ["a: {c: '1.50', d: 16}\nb: value\n"]
+				}
+				`,
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("data.utils_yaml_merge.test1", "output", canonical_ouputYaml),
+					resource.TestCheckResourceAttrPair("data.utils_yaml_merge.test1", "id", "data.utils_yaml_merge.test2", "id"),
+				),
+			},
+		},
+	})
+}
+
+const canonical_ouputYaml = `a:
+  c: "1.50"
+  d: 16
+b: value
+`
+
+func TestAccDataSourceUtilsYamlMerge_inputFormat(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: `
+				locals {
+					toml = <<-EOT
+						[tenant]
+						name = "tenant1"
+						mtu = 1500
+					EOT
+				}
+
+				data "utils_yaml_merge" "test" {
+					input        = [local.toml, "{\"tenant\": {\"vrf\": \"vrf1\"}}", "tenant:\n  mtu: 9000\n"]
+					input_format = ["toml", "json", "yaml"]
+				}
+				`,
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("data.utils_yaml_merge.test", "output", inputFormat_ouputYaml),
+				),
+			},
+			{
+				Config: `
+				data "utils_yaml_merge" "test" {
+					input        = ["a = 1"]
+					input_format = ["ini"]
+				}
+				`,
+				ExpectError: regexp.MustCompile(`Invalid input format`),
+			},
+		},
+	})
+}
+
+const inputFormat_ouputYaml = `tenant:
+    mtu: 9000
+    name: tenant1
+    vrf: vrf1
+`
+
+// Terraform defers the read of data sources with unknown inputs until apply.
+func TestAccDataSourceUtilsYamlMerge_unknownInput(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		TerraformVersionChecks: []tfversion.TerraformVersionCheck{
+			tfversion.SkipBelow(tfversion.Version1_4_0),
+		},
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: `
+				resource "terraform_data" "test" {
+					input = "b: 2\n"
+				}
+
+				data "utils_yaml_merge" "test" {
+					input = ["a: 1\n", terraform_data.test.output]
+				}
+				`,
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("data.utils_yaml_merge.test", "output", "a: 1\nb: 2\n"),
+				),
+			},
+		},
+	})
+}
+
+// Read is only called with known inputs, unknown inputs are reported as
+// errors instead of being merged as empty strings.
+func TestDataSourceUtilsYamlMergeRead(t *testing.T) {
+	cases := []struct {
+		input  []interface{}
+		output interface{}
+		err    string
+	}{
+		{
+			input:  []interface{}{"a: 1\n", "b: 2\n"},
+			output: "a: 1\nb: 2\n",
+		},
+		{
+			input: []interface{}{"a: 1\n", tftypes.UnknownValue},
+			err:   "Value Conversion Error",
+		},
+	}
+
+	ctx := context.Background()
+	d := NewYamlMergeDataSource()
+	schemaResp := &datasource.SchemaResponse{}
+	d.Schema(ctx, datasource.SchemaRequest{}, schemaResp)
+	objectType := schemaResp.Schema.Type().TerraformType(ctx).(tftypes.Object)
+
+	for _, c := range cases {
+		values := map[string]tftypes.Value{}
+		for name, attributeType := range objectType.AttributeTypes {
+			values[name] = tftypes.NewValue(attributeType, nil)
+		}
+		inputs := []tftypes.Value{}
+		for _, input := range c.input {
+			inputs = append(inputs, tftypes.NewValue(tftypes.String, input))
+		}
+		values["input"] = tftypes.NewValue(tftypes.List{ElementType: tftypes.String}, inputs)
+		req := datasource.ReadRequest{
+			Config: tfsdk.Config{Schema: schemaResp.Schema, Raw: tftypes.NewValue(objectType, values)},
+		}
+		resp := &datasource.ReadResponse{
+			State: tfsdk.State{Schema: schemaResp.Schema, Raw: tftypes.NewValue(objectType, nil)},
+		}
+		d.Read(ctx, req, resp)
+		if c.err != "" {
+			if !resp.Diagnostics.HasError() || !strings.Contains(resp.Diagnostics.Errors()[0].Summary(), c.err) {
+				t.Fatalf("Error matching error: %#v vs %s", resp.Diagnostics.Errors(), c.err)
+			}
+			continue
+		}
+		if resp.Diagnostics.HasError() {
+			t.Fatalf("Error reading data source: %#v", resp.Diagnostics.Errors())
+		}
+
+		var state map[string]tftypes.Value
+		err := resp.State.Raw.As(&state)
+		if err != nil {
+			t.Fatalf("Error reading state: %s", err)
+		}
+		if !state["output"].Equal(tftypes.NewValue(tftypes.String, c.output)) {
+			t.Fatalf("Error matching output: %#v vs %#v", state["output"], c.output)
+		}
 	}
+}
+
+func testAccDataSourceUtilsYamlMerge_config(yaml1, yaml2 string, variables map[string]string) string {
 	return fmt.Sprintf(`
 	locals {
 		yaml1 = <<-EOT%sEOT
@@ -34,14 +582,29 @@ func testAccDataSourceUtilsYamlMerge_config(yaml1, yaml2 string, envs map[string
 	}
 
 	data "utils_yaml_merge" "test" {
-		input = [local.yaml1, local.yaml2]
+		input     = [local.yaml1, local.yaml2]
+		variables = %s
+	}
+	`, yaml1, yaml2, testAccVariables(variables))
+}
+
+// testAccVariables returns variables as Terraform map.
+func testAccVariables(variables map[string]string) string {
+	keys := make([]string, 0, len(variables))
+	for k := range variables {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	items := make([]string, len(keys))
+	for i, k := range keys {
+		items[i] = fmt.Sprintf("%s = %q", k, variables[k])
 	}
-	`, yaml1, yaml2)
+	return "{" + strings.Join(items, ", ") + "}"
 }
 
 const basic_inputYaml1 = `
 root:
-  elem1: !env ELEM1
+  elem1: !var ELEM1
   child1:
     cc1: 1
 list:
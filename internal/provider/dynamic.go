@@ -0,0 +1,141 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/big"
+	"reflect"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// DynamicValue converts a decoded YAML value into a Terraform value, where
+// maps are converted to objects and lists to tuples.
+func DynamicValue(ctx context.Context, v interface{}) (attr.Value, error) {
+	if v == nil {
+		return types.DynamicNull(), nil
+	}
+	switch t := v.(type) {
+	case string:
+		return types.StringValue(t), nil
+	case bool:
+		return types.BoolValue(t), nil
+	case time.Time:
+		return types.StringValue(t.Format(time.RFC3339Nano)), nil
+	}
+	value := reflect.ValueOf(v)
+	switch value.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return types.NumberValue(new(big.Float).SetInt64(value.Int())), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return types.NumberValue(new(big.Float).SetUint64(value.Uint())), nil
+	case reflect.Float32, reflect.Float64:
+		if math.IsNaN(value.Float()) || math.IsInf(value.Float(), 0) {
+			return nil, fmt.Errorf("number %v cannot be represented in Terraform", v)
+		}
+		return types.NumberValue(big.NewFloat(value.Float())), nil
+	case reflect.Slice:
+		elementTypes := make([]attr.Type, value.Len())
+		elements := make([]attr.Value, value.Len())
+		for i := 0; i < value.Len(); i++ {
+			element, err := DynamicValue(ctx, value.Index(i).Interface())
+			if err != nil {
+				return nil, err
+			}
+			elementTypes[i] = element.Type(ctx)
+			elements[i] = element
+		}
+		tuple, diags := types.TupleValue(elementTypes, elements)
+		if diags.HasError() {
+			return nil, fmt.Errorf("error converting list: %v", diags)
+		}
+		return tuple, nil
+	case reflect.Map:
+		attributeTypes := make(map[string]attr.Type, value.Len())
+		attributes := make(map[string]attr.Value, value.Len())
+		iter := value.MapRange()
+		for iter.Next() {
+			key := fmt.Sprint(iter.Key().Interface())
+			attribute, err := DynamicValue(ctx, iter.Value().Interface())
+			if err != nil {
+				return nil, err
+			}
+			attributeTypes[key] = attribute.Type(ctx)
+			attributes[key] = attribute
+		}
+		object, diags := types.ObjectValue(attributeTypes, attributes)
+		if diags.HasError() {
+			return nil, fmt.Errorf("error converting map: %v", diags)
+		}
+		return object, nil
+	}
+	return nil, fmt.Errorf("unsupported value of type %T", v)
+}
+
+// GoValue converts a Terraform value into a Go value, where objects and maps
+// are converted to maps and lists, sets and tuples to slices. Whole numbers
+// are converted to int64, other numbers to float64.
+func GoValue(ctx context.Context, v attr.Value) (interface{}, error) {
+	if v.IsUnknown() {
+		return nil, fmt.Errorf("value is unknown")
+	}
+	if v.IsNull() {
+		return nil, nil
+	}
+	switch t := v.(type) {
+	case types.Dynamic:
+		return GoValue(ctx, t.UnderlyingValue())
+	case types.String:
+		return t.ValueString(), nil
+	case types.Bool:
+		return t.ValueBool(), nil
+	case types.Int64:
+		return t.ValueInt64(), nil
+	case types.Float64:
+		return t.ValueFloat64(), nil
+	case types.Number:
+		if i, accuracy := t.ValueBigFloat().Int64(); accuracy == big.Exact {
+			return i, nil
+		}
+		f, _ := t.ValueBigFloat().Float64()
+		return f, nil
+	case types.List:
+		return goSlice(ctx, t.Elements())
+	case types.Set:
+		return goSlice(ctx, t.Elements())
+	case types.Tuple:
+		return goSlice(ctx, t.Elements())
+	case types.Map:
+		return goMap(ctx, t.Elements())
+	case types.Object:
+		return goMap(ctx, t.Attributes())
+	}
+	return nil, fmt.Errorf("unsupported value of type %s", v.Type(ctx))
+}
+
+func goSlice(ctx context.Context, elements []attr.Value) (interface{}, error) {
+	result := make([]interface{}, len(elements))
+	for i, element := range elements {
+		value, err := GoValue(ctx, element)
+		if err != nil {
+			return nil, err
+		}
+		result[i] = value
+	}
+	return result, nil
+}
+
+func goMap(ctx context.Context, elements map[string]attr.Value) (interface{}, error) {
+	result := make(map[string]interface{}, len(elements))
+	for key, element := range elements {
+		value, err := GoValue(ctx, element)
+		if err != nil {
+			return nil, err
+		}
+		result[key] = value
+	}
+	return result, nil
+}
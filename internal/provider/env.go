@@ -0,0 +1,246 @@
+package provider
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// getenv returns the value of an environment variable. Variables of vars,
+// e.g. of env files, take precedence over the process environment.
+func getenv(vars map[string]string, name string) string {
+	if value, ok := vars[name]; ok {
+		return value
+	}
+	return os.Getenv(name)
+}
+
+// MergeEnv returns the variables of all maps, where variables of later maps
+// override variables of earlier maps, e.g. variables of the env files of a
+// data source override variables of the env files of the provider.
+func MergeEnv(vars ...map[string]string) map[string]string {
+	merged := map[string]string{}
+	for _, v := range vars {
+		for name, value := range v {
+			merged[name] = value
+		}
+	}
+	return merged
+}
+
+// LoadEnvFiles reads the variables of `.env` and Java `.properties` files,
+// where variables of later files override variables of earlier files. Files
+// ending in `.properties` are read as properties files, all other files as
+// dotenv files.
+func LoadEnvFiles(paths []string) (map[string]string, error) {
+	vars := map[string]string{}
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		if strings.HasSuffix(path, ".properties") {
+			err = parseProperties(string(data), vars)
+		} else {
+			err = parseDotenv(string(data), vars)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", path, err)
+		}
+	}
+	return vars, nil
+}
+
+var dotenvNameRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.-]*$`)
+var dotenvVarRegex = regexp.MustCompile(`\\?\$(\{[A-Za-z_][A-Za-z0-9_]*\}|[A-Za-z_][A-Za-z0-9_]*)`)
+
+// parseDotenv adds the variables of a dotenv file to vars. Lines have the
+// form `[export] NAME=value`, `#` starts a comment. Single quoted values are
+// literal, double quoted values support escape sequences (`\n`, `\t`, `\"`,
+// `\\`, `\$`). Unquoted and double quoted values expand `${NAME}` and `$NAME`
+// with previously defined variables or the process environment. Quoted
+// values can span multiple lines.
+func parseDotenv(data string, vars map[string]string) error {
+	lookup := func(name string) string {
+		if value, ok := vars[name]; ok {
+			return value
+		}
+		return os.Getenv(name)
+	}
+	expand := func(s string) string {
+		return dotenvVarRegex.ReplaceAllStringFunc(s, func(m string) string {
+			if m[0] == '\\' {
+				return m[1:]
+			}
+			return lookup(strings.Trim(m[1:], "{}"))
+		})
+	}
+
+	line := 1
+	for len(data) > 0 {
+		end := strings.IndexByte(data, '\n')
+		if end < 0 {
+			end = len(data)
+		}
+		current := strings.TrimSpace(data[:end])
+		if current == "" || current[0] == '#' {
+			data = data[min(end+1, len(data)):]
+			line++
+			continue
+		}
+		current = strings.TrimPrefix(current, "export ")
+		eq := strings.IndexByte(current, '=')
+		if eq < 0 {
+			return fmt.Errorf("line %d: missing '='", line)
+		}
+		name := strings.TrimSpace(current[:eq])
+		if !dotenvNameRegex.MatchString(name) {
+			return fmt.Errorf("line %d: invalid variable name '%s'", line, name)
+		}
+
+		// the value might span multiple lines, so it is parsed from data
+		rest := strings.TrimLeft(data[strings.IndexByte(data, '=')+1:], " \t")
+		var value string
+		if len(rest) > 0 && (rest[0] == '\'' || rest[0] == '"') {
+			quote := rest[0]
+			var b strings.Builder
+			j := 1
+			for ; j < len(rest) && rest[j] != quote; j++ {
+				if quote == '"' && rest[j] == '\\' && j+1 < len(rest) {
+					j++
+					switch rest[j] {
+					case 'n':
+						b.WriteByte('\n')
+					case 'r':
+						b.WriteByte('\r')
+					case 't':
+						b.WriteByte('\t')
+					case '$':
+						// keep the escape for expand
+						b.WriteString(`\$`)
+					default:
+						b.WriteByte(rest[j])
+					}
+					continue
+				}
+				b.WriteByte(rest[j])
+			}
+			if j >= len(rest) {
+				return fmt.Errorf("line %d: unterminated quoted value", line)
+			}
+			value = b.String()
+			if quote == '"' {
+				value = expand(value)
+			}
+			line += strings.Count(rest[:j], "\n")
+			rest = rest[j+1:]
+			end = strings.IndexByte(rest, '\n')
+			if end < 0 {
+				end = len(rest)
+			}
+			trailing := strings.TrimSpace(rest[:end])
+			if trailing != "" && trailing[0] != '#' {
+				return fmt.Errorf("line %d: unexpected characters after quoted value", line)
+			}
+		} else {
+			end = strings.IndexByte(rest, '\n')
+			if end < 0 {
+				end = len(rest)
+			}
+			value = rest[:end]
+			if comment := strings.Index(value, " #"); comment >= 0 {
+				value = value[:comment]
+			}
+			value = expand(strings.TrimSpace(value))
+		}
+		vars[name] = value
+		data = rest[min(end+1, len(rest)):]
+		line++
+	}
+	return nil
+}
+
+// parseProperties adds the properties of a Java properties file to vars.
+// Keys and values are separated by `=`, `:` or whitespace, lines starting
+// with `#` or `!` are comments and lines ending with a backslash are
+// continued on the next line. Escape sequences like `\t`, `\n` and `\uXXXX`
+// are supported in keys and values.
+func parseProperties(data string, vars map[string]string) error {
+	lines := strings.Split(strings.ReplaceAll(data, "\r\n", "\n"), "\n")
+	for j := 0; j < len(lines); j++ {
+		start := j + 1
+		current := strings.TrimLeft(lines[j], " \t\f")
+		if current == "" || current[0] == '#' || current[0] == '!' {
+			continue
+		}
+		// join continued lines, which end with an odd number of backslashes
+		for continued(current) && j+1 < len(lines) {
+			j++
+			current = current[:len(current)-1] + strings.TrimLeft(lines[j], " \t\f")
+		}
+		current = strings.TrimSuffix(current, `\`)
+
+		k := 0
+		for k < len(current) && strings.IndexByte("=: \t\f", current[k]) < 0 {
+			if current[k] == '\\' {
+				k++
+			}
+			k++
+		}
+		key, err := unescapeProperty(current[:min(k, len(current))])
+		if err != nil {
+			return fmt.Errorf("line %d: %s", start, err)
+		}
+		rest := strings.TrimLeft(current[min(k, len(current)):], " \t\f")
+		if len(rest) > 0 && (rest[0] == '=' || rest[0] == ':') {
+			rest = strings.TrimLeft(rest[1:], " \t\f")
+		}
+		value, err := unescapeProperty(rest)
+		if err != nil {
+			return fmt.Errorf("line %d: %s", start, err)
+		}
+		vars[key] = value
+	}
+	return nil
+}
+
+func continued(line string) bool {
+	n := len(line) - len(strings.TrimRight(line, `\`))
+	return n%2 == 1
+}
+
+func unescapeProperty(s string) (string, error) {
+	var b strings.Builder
+	for j := 0; j < len(s); j++ {
+		if s[j] != '\\' || j+1 >= len(s) {
+			b.WriteByte(s[j])
+			continue
+		}
+		j++
+		switch s[j] {
+		case 't':
+			b.WriteByte('\t')
+		case 'n':
+			b.WriteByte('\n')
+		case 'r':
+			b.WriteByte('\r')
+		case 'f':
+			b.WriteByte('\f')
+		case 'u':
+			if j+5 > len(s) {
+				return "", fmt.Errorf("invalid unicode escape")
+			}
+			r, err := strconv.ParseUint(s[j+1:j+5], 16, 32)
+			if err != nil {
+				return "", fmt.Errorf("invalid unicode escape '\\u%s'", s[j+1:j+5])
+			}
+			b.WriteRune(rune(r))
+			j += 4
+		default:
+			b.WriteByte(s[j])
+		}
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,161 @@
+package provider
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseDotenv(t *testing.T) {
+	t.Setenv("UTILS_TEST_HOME", "/home/user")
+	input := `# comment
+PLAIN=value
+export EXPORTED=exported
+SPACES = value with spaces   # comment
+EMPTY=
+SINGLE='literal $PLAIN \n' # comment
+DOUBLE="line1\nline2 \"quoted\" ${PLAIN}"
+MULTI="first
+second"
+EXPANDED=$UTILS_TEST_HOME/${PLAIN}
+ESCAPED=\$PLAIN
+HASH=value#not-a-comment
+`
+	expected := map[string]string{
+		"PLAIN":    "value",
+		"EXPORTED": "exported",
+		"SPACES":   "value with spaces",
+		"EMPTY":    "",
+		"SINGLE":   `literal $PLAIN \n`,
+		"DOUBLE":   "line1\nline2 \"quoted\" value",
+		"MULTI":    "first\nsecond",
+		"EXPANDED": "/home/user/value",
+		"ESCAPED":  "$PLAIN",
+		"HASH":     "value#not-a-comment",
+	}
+
+	vars := map[string]string{}
+	err := parseDotenv(input, vars)
+	if err != nil {
+		t.Fatalf("Error parsing dotenv file: %s", err)
+	}
+	if !reflect.DeepEqual(vars, expected) {
+		t.Fatalf("Error matching variables: %#v vs %#v", vars, expected)
+	}
+}
+
+func TestParseDotenvErrors(t *testing.T) {
+	cases := []struct {
+		input string
+		err   string
+	}{
+		{
+			input: "A=1\nINVALID\n",
+			err:   "line 2: missing '='",
+		},
+		{
+			input: "A=1\n1A=2\n",
+			err:   "line 2: invalid variable name '1A'",
+		},
+		{
+			input: "A=\"unterminated\nB=2\n",
+			err:   "line 1: unterminated quoted value",
+		},
+		{
+			input: "A=\"multi\nline\" trailing\n",
+			err:   "line 2: unexpected characters after quoted value",
+		},
+	}
+
+	for _, c := range cases {
+		err := parseDotenv(c.input, map[string]string{})
+		if err == nil || !strings.Contains(err.Error(), c.err) {
+			t.Fatalf("Error matching error: %v vs %s", err, c.err)
+		}
+	}
+}
+
+func TestParseProperties(t *testing.T) {
+	input := `# comment
+! comment
+key1=value1
+key2 = value2
+key3: value3
+key4 value4
+key\ 5=value\ 5
+multi = first, \
+        second
+unicode = caf\u00e9
+tab = a\tb
+empty =
+`
+	expected := map[string]string{
+		"key1":    "value1",
+		"key2":    "value2",
+		"key3":    "value3",
+		"key4":    "value4",
+		"key 5":   "value 5",
+		"multi":   "first, second",
+		"unicode": "café",
+		"tab":     "a\tb",
+		"empty":   "",
+	}
+
+	vars := map[string]string{}
+	err := parseProperties(input, vars)
+	if err != nil {
+		t.Fatalf("Error parsing properties file: %s", err)
+	}
+	if !reflect.DeepEqual(vars, expected) {
+		t.Fatalf("Error matching variables: %#v vs %#v", vars, expected)
+	}
+
+	err = parseProperties("key = \\uZZZZ\n", map[string]string{})
+	if err == nil || !strings.Contains(err.Error(), "line 1: invalid unicode escape") {
+		t.Fatalf("Error matching error: %v", err)
+	}
+}
+
+func TestEnvFiles(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, ".env"), []byte("NAME=dotenv\nSTAGE=dev\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "app.properties"), []byte("STAGE = prod\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "provider.env"), []byte("NAME=provider\nREGION=eu\nZONE=a\n"), 0644)
+	t.Setenv("NAME", "process")
+	t.Setenv("ZONE", "b")
+	t.Setenv("OTHER", "process")
+
+	vars, err := LoadEnvFiles([]string{filepath.Join(dir, ".env"), filepath.Join(dir, "app.properties")})
+	if err != nil {
+		t.Fatalf("Error reading env files: %s", err)
+	}
+	providerVars, err := LoadEnvFiles([]string{filepath.Join(dir, "provider.env")})
+	if err != nil {
+		t.Fatalf("Error reading env files: %s", err)
+	}
+
+	input := "name: !env NAME\nstage: !env STAGE\nregion: !env REGION\nzone: !env ZONE\nother: !env OTHER\n"
+	result := map[string]interface{}{
+		"name":   "dotenv",
+		"stage":  "prod",
+		"region": "eu",
+		"zone":   "a",
+		"other":  "process",
+	}
+
+	var data map[string]interface{}
+	err = YamlUnmarshalWithOptions([]byte(input), &data, YamlOptions{Env: MergeEnv(providerVars, vars)})
+	if err != nil {
+		t.Fatalf("Error reading YAML string: %s", err)
+	}
+	if !reflect.DeepEqual(data, result) {
+		t.Fatalf("Error matching data and result: %#v vs %#v", data, result)
+	}
+
+	_, err = LoadEnvFiles([]string{filepath.Join(dir, "missing.env")})
+	if err == nil {
+		t.Fatalf("Expected error reading missing env file")
+	}
+}
@@ -0,0 +1,86 @@
+package provider
+
+import (
+	"context"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/ephemeral"
+	"github.com/hashicorp/terraform-plugin-framework/ephemeral/schema"
+)
+
+var _ ephemeral.EphemeralResource = (*yamlMergeEphemeralResource)(nil)
+var _ ephemeral.EphemeralResourceWithConfigure = (*yamlMergeEphemeralResource)(nil)
+
+func NewYamlMergeEphemeralResource() ephemeral.EphemeralResource {
+	return &yamlMergeEphemeralResource{}
+}
+
+type yamlMergeEphemeralResource struct {
+	data *utilsProviderData
+}
+
+func (e *yamlMergeEphemeralResource) Metadata(_ context.Context, req ephemeral.MetadataRequest, resp *ephemeral.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_yaml_merge"
+}
+
+func (e *yamlMergeEphemeralResource) Configure(_ context.Context, req ephemeral.ConfigureRequest, resp *ephemeral.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+	e.data = req.ProviderData.(*utilsProviderData)
+}
+
+func (e *yamlMergeEphemeralResource) Schema(ctx context.Context, req ephemeral.SchemaRequest, resp *ephemeral.SchemaResponse) {
+	attributes := yamlMergeAttributes(ephemeralAttributes)
+	// the id of the output is only meaningful for values in the state
+	delete(attributes, "id")
+
+	resp.Schema = schema.Schema{
+		// This description is used by the documentation generator and the language server.
+		MarkdownDescription: "Merge a list of YAML strings like the `utils_yaml_merge` data source without persisting the output to the state or plan, e.g. if inputs contain secrets resolved by YAML `!env` tags. The output can be used in provider configurations, write-only attributes and other ephemeral contexts.",
+
+		Attributes: attributes,
+	}
+}
+
+// ephemeralAttributes builds ephemeral resource attributes.
+var ephemeralAttributes = attributeBuilder[schema.Attribute]{
+	String: func(s attributeSpec) schema.Attribute {
+		return schema.StringAttribute{Description: s.Description, Required: s.Required, Optional: s.Optional, Computed: s.Computed}
+	},
+	Bool: func(s attributeSpec) schema.Attribute {
+		return schema.BoolAttribute{Description: s.Description, Required: s.Required, Optional: s.Optional, Computed: s.Computed}
+	},
+	Int64: func(s attributeSpec) schema.Attribute {
+		return schema.Int64Attribute{Description: s.Description, Required: s.Required, Optional: s.Optional, Computed: s.Computed}
+	},
+	List: func(s attributeSpec, elementType attr.Type) schema.Attribute {
+		return schema.ListAttribute{Description: s.Description, ElementType: elementType, Required: s.Required, Optional: s.Optional, Computed: s.Computed}
+	},
+	Map: func(s attributeSpec, elementType attr.Type) schema.Attribute {
+		return schema.MapAttribute{Description: s.Description, ElementType: elementType, Required: s.Required, Optional: s.Optional, Computed: s.Computed}
+	},
+	Dynamic: func(s attributeSpec) schema.Attribute {
+		return schema.DynamicAttribute{Description: s.Description, Required: s.Required, Optional: s.Optional, Computed: s.Computed}
+	},
+}
+
+func (e *yamlMergeEphemeralResource) Open(ctx context.Context, req ephemeral.OpenRequest, resp *ephemeral.OpenResponse) {
+	var config YamlMergeBase
+
+	// Read config
+	diags := req.Config.Get(ctx, &config)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	_, diags = config.read(ctx, e.data)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	diags = resp.Result.Set(ctx, &config)
+	resp.Diagnostics.Append(diags...)
+}
@@ -0,0 +1,48 @@
+package provider
+
+import (
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/providerserver"
+	"github.com/hashicorp/terraform-plugin-go/tfprotov6"
+	"github.com/hashicorp/terraform-plugin-testing/echoprovider"
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+	"github.com/hashicorp/terraform-plugin-testing/knownvalue"
+	"github.com/hashicorp/terraform-plugin-testing/statecheck"
+	"github.com/hashicorp/terraform-plugin-testing/tfjsonpath"
+	"github.com/hashicorp/terraform-plugin-testing/tfversion"
+)
+
+func TestAccEphemeralResourceUtilsYamlMerge(t *testing.T) {
+	t.Setenv("ELEM1", "value1")
+	resource.Test(t, resource.TestCase{
+		TerraformVersionChecks: []tfversion.TerraformVersionCheck{
+			tfversion.SkipBelow(tfversion.Version1_10_0),
+		},
+		PreCheck: func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: map[string]func() (tfprotov6.ProviderServer, error){
+			"utils": providerserver.NewProtocol6WithError(New("test")()),
+			"echo":  echoprovider.NewProviderServer(),
+		},
+		Steps: []resource.TestStep{
+			{
+				// the output is passed to the echo provider, which is the
+				// only way to check values of ephemeral resources
+				Config: `
+				ephemeral "utils_yaml_merge" "test" {
+					input = ["a: !env ELEM1\n", "b: 2\n"]
+				}
+
+				provider "echo" {
+					data = ephemeral.utils_yaml_merge.test.output
+				}
+
+				resource "echo" "test" {}
+				`,
+				ConfigStateChecks: []statecheck.StateCheck{
+					statecheck.ExpectKnownValue("echo.test", tfjsonpath.New("data"), knownvalue.StringExact("a: value1\nb: 2\n")),
+				},
+			},
+		},
+	})
+}
@@ -0,0 +1,241 @@
+package provider
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ScalarStyles records the original representation of decoded scalars, e.g.
+// quoted strings, explicit tags, timestamps or floats like `1.0`, which are
+// otherwise lost when decoding into Go values, and the order in which keys
+// are first seen. Scalars are identified by their path, where list items
+// share the same path, and their decoded value.
+type ScalarStyles struct {
+	nodes map[string]*yaml.Node
+	keys  map[string]int
+}
+
+func NewScalarStyles() *ScalarStyles {
+	return &ScalarStyles{nodes: map[string]*yaml.Node{}, keys: map[string]int{}}
+}
+
+// collect records the scalars of a resolved node.
+func (s *ScalarStyles) collect(node *yaml.Node, path string) {
+	switch node.Kind {
+	case yaml.DocumentNode:
+		for _, child := range node.Content {
+			s.collect(child, path)
+		}
+	case yaml.AliasNode:
+		s.collect(node.Alias, path)
+	case yaml.MappingNode:
+		for j := 0; j+1 < len(node.Content); j += 2 {
+			key, value := node.Content[j], node.Content[j+1]
+			if key.ShortTag() == "!!merge" {
+				// merged maps share the path of the map
+				s.collect(value, path)
+				continue
+			}
+			var k interface{}
+			if key.Kind != yaml.ScalarNode || key.Decode(&k) != nil {
+				continue
+			}
+			s.add(path+"#", key, k)
+			s.addKey(path, k)
+			s.collect(value, path+"\x00"+fmt.Sprint(k))
+		}
+	case yaml.SequenceNode:
+		for _, child := range node.Content {
+			s.collect(child, path+"\x00[]")
+		}
+	case yaml.ScalarNode:
+		var v interface{}
+		if node.Decode(&v) == nil {
+			s.add(path+"=", node, v)
+		}
+	}
+}
+
+func (s *ScalarStyles) add(prefix string, node *yaml.Node, v interface{}) {
+	// values of custom tags are replaced by resolvers
+	if strings.HasPrefix(node.Tag, "!") && !strings.HasPrefix(node.Tag, "!!") {
+		return
+	}
+	s.nodes[prefix+scalarStyleKey(v)] = node
+}
+
+// addKey records the position of a key of the map at path, unless the key
+// has been seen before.
+func (s *ScalarStyles) addKey(path string, key interface{}) {
+	if _, ok := s.keys[path+"#"+scalarStyleKey(key)]; !ok {
+		s.keys[path+"#"+scalarStyleKey(key)] = len(s.keys)
+	}
+}
+
+// YamlMarshalOptions controls how values are encoded by
+// YamlMarshalWithOptions.
+type YamlMarshalOptions struct {
+	// Styles are the original representations of scalars, which are restored.
+	Styles *ScalarStyles
+	// Indent is the number of spaces used for indentation, defaults to 4.
+	Indent int
+	// SortKeys is the order of keys: `none` for the default order of the
+	// encoder, `alpha` or `first_seen`.
+	SortKeys string
+	// StringStyle is the style of string values: `auto`, `double_quoted`,
+	// `single_quoted` or `literal` for multi-line strings.
+	StringStyle string
+}
+
+type yamlEncoder struct {
+	options YamlMarshalOptions
+}
+
+// apply restores the original representation of scalars in node, which is
+// the encoded form of v, and applies the options.
+func (e *yamlEncoder) apply(node *yaml.Node, v interface{}, path string) {
+	value := reflect.ValueOf(v)
+	switch {
+	case node.Kind == yaml.MappingNode && value.Kind() == reflect.Map:
+		keys := make(map[string]reflect.Value, value.Len())
+		for _, k := range value.MapKeys() {
+			var n yaml.Node
+			if n.Encode(k.Interface()) == nil {
+				keys[n.Tag+":"+n.Value] = k
+			}
+		}
+		type pair struct {
+			key   interface{}
+			nodes []*yaml.Node
+		}
+		pairs := make([]pair, 0, len(node.Content)/2)
+		for j := 0; j+1 < len(node.Content); j += 2 {
+			p := pair{nodes: node.Content[j : j+2]}
+			if k, ok := keys[node.Content[j].Tag+":"+node.Content[j].Value]; ok {
+				p.key = k.Interface()
+				e.applyScalar(node.Content[j], p.key, path+"#", false)
+				e.apply(node.Content[j+1], value.MapIndex(k).Interface(), path+"\x00"+fmt.Sprint(p.key))
+			}
+			pairs = append(pairs, p)
+		}
+		switch e.options.SortKeys {
+		case "alpha":
+			sort.SliceStable(pairs, func(a, b int) bool {
+				return fmt.Sprint(pairs[a].key) < fmt.Sprint(pairs[b].key)
+			})
+		case "first_seen":
+			var seen map[string]int
+			if e.options.Styles != nil {
+				seen = e.options.Styles.keys
+			}
+			position := func(key interface{}) int {
+				if p, ok := seen[path+"#"+scalarStyleKey(key)]; ok {
+					return p
+				}
+				// keys not seen in the inputs, e.g. added by defaults, are last
+				return len(seen)
+			}
+			sort.SliceStable(pairs, func(a, b int) bool {
+				return position(pairs[a].key) < position(pairs[b].key)
+			})
+		}
+		content := make([]*yaml.Node, 0, len(node.Content))
+		for _, p := range pairs {
+			content = append(content, p.nodes...)
+		}
+		node.Content = content
+	case node.Kind == yaml.SequenceNode && value.Kind() == reflect.Slice:
+		for j := 0; j < len(node.Content) && j < value.Len(); j++ {
+			e.apply(node.Content[j], value.Index(j).Interface(), path+"\x00[]")
+		}
+	case node.Kind == yaml.ScalarNode:
+		e.applyScalar(node, v, path+"=", true)
+	}
+}
+
+func (e *yamlEncoder) applyScalar(node *yaml.Node, v interface{}, prefix string, isValue bool) {
+	var original *yaml.Node
+	if e.options.Styles != nil {
+		original = e.options.Styles.nodes[prefix+scalarStyleKey(v)]
+	}
+	// folded scalars cannot be restored as their line breaks are lost
+	if original != nil && original.Style&yaml.FoldedStyle == 0 {
+		node.Value = original.Value
+		node.Tag = original.Tag
+		node.Style = original.Style
+	} else if isFloat(v) && strings.Trim(node.Value, "+-0123456789") == "" {
+		// floats without fraction are encoded like integers, e.g. `1` for `1.0`
+		node.Value += ".0"
+		node.Tag = "!!float"
+	}
+	if !isValue || node.ShortTag() != "!!str" {
+		return
+	}
+	switch e.options.StringStyle {
+	case "double_quoted":
+		node.Style = yaml.DoubleQuotedStyle | node.Style&yaml.TaggedStyle
+	case "single_quoted":
+		node.Style = yaml.SingleQuotedStyle | node.Style&yaml.TaggedStyle
+	case "literal":
+		if strings.Contains(node.Value, "\n") {
+			node.Style = yaml.LiteralStyle | node.Style&yaml.TaggedStyle
+		}
+	}
+}
+
+func isFloat(v interface{}) bool {
+	switch v.(type) {
+	case float32, float64:
+		return true
+	}
+	return false
+}
+
+func scalarStyleKey(v interface{}) string {
+	return fmt.Sprintf("%T:%v", v, v)
+}
+
+// YamlMarshal encodes v like yaml.Marshal, but restores the original
+// representation of scalars recorded in styles.
+func YamlMarshal(v interface{}, styles *ScalarStyles) ([]byte, error) {
+	return YamlMarshalWithOptions(v, YamlMarshalOptions{Styles: styles})
+}
+
+func YamlMarshalWithOptions(v interface{}, options YamlMarshalOptions) ([]byte, error) {
+	node, err := encodeNode(v, options)
+	if err != nil {
+		return nil, err
+	}
+
+	var b bytes.Buffer
+	encoder := yaml.NewEncoder(&b)
+	if options.Indent > 0 {
+		encoder.SetIndent(options.Indent)
+	}
+	err = encoder.Encode(node)
+	if err != nil {
+		return nil, err
+	}
+	err = encoder.Close()
+	if err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// encodeNode encodes v into a node, where the options are applied.
+func encodeNode(v interface{}, options YamlMarshalOptions) (*yaml.Node, error) {
+	var node yaml.Node
+	err := node.Encode(v)
+	if err != nil {
+		return nil, err
+	}
+	e := &yamlEncoder{options: options}
+	e.apply(&node, v, "")
+	return &node, nil
+}
@@ -0,0 +1,149 @@
+package provider
+
+import (
+	"reflect"
+	"testing"
+)
+
+// roundTripCorpus contains YAML documents, which are expected to be returned
+// unchanged by a round trip through YamlUnmarshal and YamlMarshal.
+var roundTripCorpus = []string{
+	// floats
+	"exp: 1e3\nfloat: 1.0\nfloat2: 1.50\ninf: .inf\nnegative: -2.0\n",
+	// large integers
+	"big: 12345678901234567890\nhuge: 123456789012345678901234567890\n",
+	// octal and hexadecimal integers
+	"hex: 0xFF\noctal: 0755\noctal2: 0o755\n",
+	// octal-looking strings
+	"mode: \"0755\"\nzip: '01234'\n",
+	// timestamps
+	"date: 2001-12-14\ntagged: !!timestamp 2001-12-14\ntimestamp: 2001-12-14t21:59:43.10-05:00\n",
+	// quoting styles
+	"double: \"value\"\nplain: value\nsingle: 'value'\n",
+	// explicit tags
+	"float: !!float 1\nnumber: !!str 123\n",
+	// null values
+	"empty:\nexplicit: null\ntilde: ~\n",
+	// booleans
+	"lower: true\nupper: True\n",
+	// non-string keys
+	"true: bool\n1.5: float\n10: vlan10\n\"20\": vlan20\n",
+	// block scalars
+	"literal: |\n    line1\n    line2\n",
+	// lists of maps
+	"vlans:\n    - id: 0010\n      name: \"vlan10\"\n    - id: 20\n      name: 'vlan20'\n",
+}
+
+func TestYamlRoundTrip(t *testing.T) {
+	for _, input := range roundTripCorpus {
+		styles := NewScalarStyles()
+		var data map[interface{}]interface{}
+		err := YamlUnmarshalWithOptions([]byte(input), &data, YamlOptions{ScalarStyles: styles})
+		if err != nil {
+			t.Fatalf("Error reading YAML string: %s", err)
+		}
+		output, err := YamlMarshal(data, styles)
+		if err != nil {
+			t.Fatalf("Error converting result to YAML: %s", err)
+		}
+		if string(output) != input {
+			t.Errorf("Error matching round trip: %q vs %q", input, string(output))
+		}
+	}
+}
+
+func TestYamlMergeFidelity(t *testing.T) {
+	inputs := []string{
+		"tenant:\n    name: \"tenant1\"\n    version: 1.0\n    vlans:\n        - id: 10\n          mode: \"0755\"\n",
+		"tenant:\n    version: 2.0\n    vlans:\n        - id: 10\n          name: 'vlan10'\n        - id: 20\n",
+	}
+	expected := "tenant:\n    name: \"tenant1\"\n    version: 2.0\n    vlans:\n        - id: 10\n          mode: \"0755\"\n        - id: 10\n          name: 'vlan10'\n        - id: 20\n"
+
+	styles := NewScalarStyles()
+	merged := map[interface{}]interface{}{}
+	for _, input := range inputs {
+		var data map[interface{}]interface{}
+		err := YamlUnmarshalWithOptions([]byte(input), &data, YamlOptions{ScalarStyles: styles})
+		if err != nil {
+			t.Fatalf("Error reading YAML string: %s", err)
+		}
+		err = MergeMaps(reflect.ValueOf(merged), reflect.ValueOf(data), true)
+		if err != nil {
+			t.Fatalf("Error merging YAML: %s", err)
+		}
+	}
+	output, err := YamlMarshal(merged, styles)
+	if err != nil {
+		t.Fatalf("Error converting result to YAML: %s", err)
+	}
+	if string(output) != expected {
+		t.Fatalf("Error matching output: %q vs %q", string(output), expected)
+	}
+}
+
+func TestYamlMarshalWithOptions(t *testing.T) {
+	cases := []struct {
+		inputs   []string
+		options  YamlMarshalOptions
+		expected string
+	}{
+		{
+			inputs:   []string{"a:\n  b: c\n  d:\n    - e\n"},
+			options:  YamlMarshalOptions{Indent: 2},
+			expected: "a:\n  b: c\n  d:\n    - e\n",
+		},
+		{
+			inputs:   []string{"b: 1\nB: 2\na10: 3\na9: 4\n"},
+			options:  YamlMarshalOptions{SortKeys: "none"},
+			expected: "B: 2\na9: 4\na10: 3\nb: 1\n",
+		},
+		{
+			inputs:   []string{"b: 1\nB: 2\na10: 3\na9: 4\n"},
+			options:  YamlMarshalOptions{SortKeys: "alpha"},
+			expected: "B: 2\na10: 3\na9: 4\nb: 1\n",
+		},
+		{
+			inputs:   []string{"z: 1\nm:\n  y: 1\n  x: 2\n", "a: 1\nz: 2\nm:\n  w: 3\n"},
+			options:  YamlMarshalOptions{Indent: 2, SortKeys: "first_seen"},
+			expected: "z: 2\nm:\n  y: 1\n  x: 2\n  w: 3\na: 1\n",
+		},
+		{
+			inputs:   []string{"a: value\nb: 'quoted'\nc: 1\n"},
+			options:  YamlMarshalOptions{StringStyle: "double_quoted"},
+			expected: "a: \"value\"\nb: \"quoted\"\nc: 1\n",
+		},
+		{
+			inputs:   []string{"a: value\nb: \"quoted\"\n"},
+			options:  YamlMarshalOptions{StringStyle: "single_quoted"},
+			expected: "a: 'value'\nb: 'quoted'\n",
+		},
+		{
+			inputs:   []string{"a: \"line1\\nline2\\n\"\nb: value\n"},
+			options:  YamlMarshalOptions{Indent: 2, StringStyle: "literal"},
+			expected: "a: |\n  line1\n  line2\nb: value\n",
+		},
+	}
+
+	for _, c := range cases {
+		c.options.Styles = NewScalarStyles()
+		merged := map[interface{}]interface{}{}
+		for _, input := range c.inputs {
+			var data map[interface{}]interface{}
+			err := YamlUnmarshalWithOptions([]byte(input), &data, YamlOptions{ScalarStyles: c.options.Styles})
+			if err != nil {
+				t.Fatalf("Error reading YAML string: %s", err)
+			}
+			err = MergeMaps(reflect.ValueOf(merged), reflect.ValueOf(data), true)
+			if err != nil {
+				t.Fatalf("Error merging YAML: %s", err)
+			}
+		}
+		output, err := YamlMarshalWithOptions(merged, c.options)
+		if err != nil {
+			t.Fatalf("Error converting result to YAML: %s", err)
+		}
+		if string(output) != c.expected {
+			t.Fatalf("Error matching output: %q vs %q", string(output), c.expected)
+		}
+	}
+}
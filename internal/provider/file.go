@@ -0,0 +1,95 @@
+package provider
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+// fileResolvers returns the resolvers of tags embedding file content, where
+// paths are relative to baseDir.
+func fileResolvers(baseDir string) map[string]func(*yaml.Node) (*yaml.Node, error) {
+	return map[string]func(*yaml.Node) (*yaml.Node, error){
+		"!file": func(node *yaml.Node) (*yaml.Node, error) {
+			content, err := readScalarFile(node, "!file", baseDir)
+			if err != nil {
+				return nil, err
+			}
+			node.Value = string(content)
+			return node, nil
+		},
+		"!base64": func(node *yaml.Node) (*yaml.Node, error) {
+			if node.Kind != yaml.ScalarNode {
+				return nil, errors.New("!base64 on a non-scalar node")
+			}
+			node.Value = base64.StdEncoding.EncodeToString([]byte(node.Value))
+			return node, nil
+		},
+		"!base64file": func(node *yaml.Node) (*yaml.Node, error) {
+			content, err := readScalarFile(node, "!base64file", baseDir)
+			if err != nil {
+				return nil, err
+			}
+			node.Value = base64.StdEncoding.EncodeToString(content)
+			return node, nil
+		},
+		"!filehash": func(node *yaml.Node) (*yaml.Node, error) {
+			content, err := readScalarFile(node, "!filehash", baseDir)
+			if err != nil {
+				return nil, err
+			}
+			checksum := sha256.Sum256(content)
+			node.Value = hex.EncodeToString(checksum[:])
+			return node, nil
+		},
+	}
+}
+
+func readScalarFile(node *yaml.Node, tag, baseDir string) ([]byte, error) {
+	if node.Kind != yaml.ScalarNode {
+		return nil, fmt.Errorf("%s on a non-scalar node", tag)
+	}
+	content, err := readFile(baseDir, node.Value)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", tag, err)
+	}
+	return content, nil
+}
+
+// readFile reads a file relative to baseDir. Files outside of baseDir, also
+// via symbolic links, cannot be read.
+func readFile(baseDir, path string) ([]byte, error) {
+	if baseDir == "" {
+		baseDir = "."
+	}
+	if !filepath.IsLocal(path) {
+		return nil, fmt.Errorf("path '%s' is outside of the base directory", path)
+	}
+	base, err := filepath.EvalSymlinks(baseDir)
+	if err != nil {
+		return nil, err
+	}
+	resolved, err := filepath.EvalSymlinks(filepath.Join(base, path))
+	if err != nil {
+		return nil, fmt.Errorf("file '%s' not found", path)
+	}
+	base, err = filepath.Abs(base)
+	if err != nil {
+		return nil, err
+	}
+	resolved, err = filepath.Abs(resolved)
+	if err != nil {
+		return nil, err
+	}
+	rel, err := filepath.Rel(base, resolved)
+	if err != nil || !filepath.IsLocal(rel) {
+		return nil, fmt.Errorf("path '%s' is outside of the base directory", path)
+	}
+	return os.ReadFile(resolved)
+}
@@ -0,0 +1,74 @@
+package provider
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestYamlUnmarshalFiles(t *testing.T) {
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "base", "certs"), 0755)
+	os.WriteFile(filepath.Join(dir, "base", "certs", "cert.pem"), []byte("line1\nline2\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "secret"), []byte("secret"), 0644)
+	os.Symlink(filepath.Join(dir, "secret"), filepath.Join(dir, "base", "link"))
+	options := YamlOptions{BaseDir: filepath.Join(dir, "base")}
+
+	input := `
+file: !file certs/cert.pem
+base64: !base64 value
+base64file: !base64file certs/../certs/cert.pem
+filehash: !filehash certs/cert.pem
+`
+	result := map[string]interface{}{
+		"file":       "line1\nline2\n",
+		"base64":     "dmFsdWU=",
+		"base64file": "bGluZTEKbGluZTIK",
+		"filehash":   "2751a3a2f303ad21752038085e2b8c5f98ecff61a2e4ebbd43506a941725be80",
+	}
+
+	var data map[string]interface{}
+	err := YamlUnmarshalWithOptions([]byte(input), &data, options)
+	if err != nil {
+		t.Fatalf("Error reading YAML string: %s", err)
+	}
+	if !reflect.DeepEqual(data, result) {
+		t.Fatalf("Error matching data and result: %#v vs %#v", data, result)
+	}
+
+	cases := []struct {
+		input string
+		err   string
+	}{
+		{
+			input: "file: !file ../secret\n",
+			err:   "!file: path '../secret' is outside of the base directory",
+		},
+		{
+			input: "file: !file " + filepath.Join(dir, "secret") + "\n",
+			err:   "is outside of the base directory",
+		},
+		{
+			input: "file: !base64file link\n",
+			err:   "!base64file: path 'link' is outside of the base directory",
+		},
+		{
+			input: "file: !filehash missing\n",
+			err:   "!filehash: file 'missing' not found",
+		},
+		{
+			input: "file: !file [a]\n",
+			err:   "!file on a non-scalar node",
+		},
+	}
+
+	for _, c := range cases {
+		var data map[string]interface{}
+		err := YamlUnmarshalWithOptions([]byte(c.input), &data, options)
+		if err == nil || !strings.Contains(err.Error(), c.err) {
+			t.Fatalf("Error matching error: %v vs %s", err, c.err)
+		}
+	}
+}
@@ -0,0 +1,219 @@
+package provider
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/zclconf/go-cty/cty"
+	"gopkg.in/yaml.v3"
+)
+
+// inputFormats are the supported formats of inputs.
+var inputFormats = []string{"yaml", "json", "toml", "hcl"}
+
+func isInputFormat(format string) bool {
+	for _, f := range inputFormats {
+		if f == format {
+			return true
+		}
+	}
+	return false
+}
+
+// UnmarshalWithFormat decodes in, which is a YAML, JSON, TOML or HCL string,
+// into out. Non-YAML inputs are decoded into the same types as YAML strings,
+// so that they can be merged with each other. YAML tags and the options are
+// only supported for YAML strings, except ScalarStyles, which also records
+// the order of keys of JSON and TOML strings.
+func UnmarshalWithFormat(format string, in []byte, out interface{}, options YamlOptions) error {
+	var node *yaml.Node
+	var err error
+	switch format {
+	case "", "yaml":
+		return YamlUnmarshalWithOptions(in, out, options)
+	case "json":
+		node, err = decodeJSON(in)
+	case "toml":
+		node, err = decodeTOML(in, options.ScalarStyles)
+	case "hcl":
+		node, err = decodeHCL(in, options.Name)
+	default:
+		return fmt.Errorf("unknown format '%s'", format)
+	}
+	if err != nil {
+		return err
+	}
+	// nodes of TOML and HCL strings are encoded from decoded values and do not
+	// have an original representation
+	if options.ScalarStyles != nil && format == "json" {
+		options.ScalarStyles.collect(node, "")
+	}
+	return node.Decode(out)
+}
+
+// decodeJSON decodes a JSON string into a node, which keeps the order of keys
+// and the representation of numbers.
+func decodeJSON(in []byte) (*yaml.Node, error) {
+	decoder := json.NewDecoder(bytes.NewReader(in))
+	decoder.UseNumber()
+	node, err := jsonNode(decoder)
+	if err == nil {
+		if _, err = decoder.Token(); err == nil {
+			err = fmt.Errorf("invalid character after top-level value")
+		} else if errors.Is(err, io.EOF) {
+			err = nil
+		}
+	}
+	if errors.Is(err, io.EOF) {
+		if node == nil && len(bytes.TrimSpace(in)) == 0 {
+			return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null"}, nil
+		}
+		err = io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		line := 1 + bytes.Count(in[:decoder.InputOffset()], []byte("\n"))
+		var syntaxErr *json.SyntaxError
+		if errors.As(err, &syntaxErr) {
+			line = 1 + bytes.Count(in[:syntaxErr.Offset], []byte("\n"))
+		}
+		return nil, fmt.Errorf("json: line %d: %s", line, err)
+	}
+	return node, nil
+}
+
+func jsonNode(decoder *json.Decoder) (*yaml.Node, error) {
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch t := token.(type) {
+	case json.Delim:
+		node := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+		if t == '{' {
+			node = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		}
+		for decoder.More() {
+			if node.Kind == yaml.MappingNode {
+				key, err := decoder.Token()
+				if err != nil {
+					return nil, err
+				}
+				node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key.(string)})
+			}
+			item, err := jsonNode(decoder)
+			if err != nil {
+				return nil, err
+			}
+			node.Content = append(node.Content, item)
+		}
+		// closing delimiter
+		_, err = decoder.Token()
+		return node, err
+	case string:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: t}, nil
+	case json.Number:
+		tag := "!!int"
+		if strings.ContainsAny(t.String(), ".eE") {
+			tag = "!!float"
+		}
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: tag, Value: t.String()}, nil
+	case bool:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!bool", Value: fmt.Sprint(t)}, nil
+	}
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null", Value: "null"}, nil
+}
+
+// decodeTOML decodes a TOML string into a node. The order of keys is
+// recorded in styles.
+func decodeTOML(in []byte, styles *ScalarStyles) (*yaml.Node, error) {
+	var v interface{}
+	md, err := toml.Decode(string(in), &v)
+	if err != nil {
+		return nil, err
+	}
+	if styles != nil {
+		for _, key := range md.Keys() {
+			path := ""
+			for j := 0; j < len(key)-1; j++ {
+				path += "\x00" + key[j]
+				if md.Type(key[:j+1]...) == "ArrayHash" {
+					path += "\x00[]"
+				}
+			}
+			styles.addKey(path, key[len(key)-1])
+		}
+	}
+	// floats without fraction are kept as floats
+	return encodeNode(v, YamlMarshalOptions{})
+}
+
+// decodeHCL decodes the attributes of an HCL file. Blocks, variables and
+// function calls are not supported.
+func decodeHCL(in []byte, name string) (*yaml.Node, error) {
+	if name == "" {
+		name = "input"
+	}
+	file, diags := hclsyntax.ParseConfig(in, name, hcl.Pos{Line: 1, Column: 1})
+	if diags.HasErrors() {
+		return nil, diags
+	}
+	attrs, diags := file.Body.JustAttributes()
+	if diags.HasErrors() {
+		return nil, diags
+	}
+	result := make(map[string]interface{}, len(attrs))
+	for key, attr := range attrs {
+		value, diags := attr.Expr.Value(nil)
+		if diags.HasErrors() {
+			return nil, diags
+		}
+		result[key] = ctyGoValue(value)
+	}
+	return encodeNode(result, YamlMarshalOptions{})
+}
+
+// ctyGoValue converts a known cty value to the Go types used by YAML.
+func ctyGoValue(v cty.Value) interface{} {
+	if v.IsNull() {
+		return nil
+	}
+	t := v.Type()
+	switch {
+	case t == cty.String:
+		return v.AsString()
+	case t == cty.Bool:
+		return v.True()
+	case t == cty.Number:
+		f := v.AsBigFloat()
+		if f.IsInt() {
+			if i, accuracy := f.Int64(); accuracy == big.Exact {
+				return int(i)
+			}
+		}
+		n, _ := f.Float64()
+		return n
+	case t.IsObjectType() || t.IsMapType():
+		result := map[string]interface{}{}
+		for it := v.ElementIterator(); it.Next(); {
+			k, e := it.Element()
+			result[k.AsString()] = ctyGoValue(e)
+		}
+		return result
+	case t.IsTupleType() || t.IsListType() || t.IsSetType():
+		result := []interface{}{}
+		for it := v.ElementIterator(); it.Next(); {
+			_, e := it.Element()
+			result = append(result, ctyGoValue(e))
+		}
+		return result
+	}
+	return nil
+}
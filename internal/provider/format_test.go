@@ -0,0 +1,115 @@
+package provider
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestUnmarshalWithFormat(t *testing.T) {
+	expected := map[interface{}]interface{}{
+		"name":    "tenant1",
+		"enabled": true,
+		"mtu":     9000,
+		"ratio":   1.5,
+		"vrfs": []interface{}{
+			map[string]interface{}{"name": "vrf1", "vlans": []interface{}{10, 20}},
+		},
+		"empty": nil,
+	}
+	cases := []struct {
+		format string
+		input  string
+	}{
+		{
+			format: "yaml",
+			input:  "name: tenant1\nenabled: true\nmtu: 9000\nratio: 1.5\nvrfs:\n  - name: vrf1\n    vlans: [10, 20]\nempty:\n",
+		},
+		{
+			format: "json",
+			input:  `{"name": "tenant1", "enabled": true, "mtu": 9000, "ratio": 1.5, "vrfs": [{"name": "vrf1", "vlans": [10, 20]}], "empty": null}`,
+		},
+		{
+			format: "hcl",
+			input:  "name = \"tenant1\"\nenabled = true\nmtu = 9000\nratio = 1.5\nvrfs = [{ name = \"vrf1\", vlans = [10, 20] }]\nempty = null\n",
+		},
+	}
+
+	for _, c := range cases {
+		var data map[interface{}]interface{}
+		err := UnmarshalWithFormat(c.format, []byte(c.input), &data, YamlOptions{})
+		if err != nil {
+			t.Fatalf("Error reading %s string: %s", c.format, err)
+		}
+		if !reflect.DeepEqual(data, expected) {
+			t.Fatalf("Error matching %s: %#v vs %#v", c.format, data, expected)
+		}
+	}
+}
+
+func TestUnmarshalWithFormatToml(t *testing.T) {
+	input := "name = \"tenant1\"\nmtu = 9000\n\n[[vrfs]]\nname = \"vrf1\"\nvlans = [10, 20]\n"
+	expected := map[interface{}]interface{}{
+		"name": "tenant1",
+		"mtu":  9000,
+		"vrfs": []interface{}{
+			map[string]interface{}{"name": "vrf1", "vlans": []interface{}{10, 20}},
+		},
+	}
+
+	var data map[interface{}]interface{}
+	err := UnmarshalWithFormat("toml", []byte(input), &data, YamlOptions{})
+	if err != nil {
+		t.Fatalf("Error reading TOML string: %s", err)
+	}
+	if !reflect.DeepEqual(data, expected) {
+		t.Fatalf("Error matching TOML: %#v vs %#v", data, expected)
+	}
+}
+
+func TestUnmarshalWithFormatErrors(t *testing.T) {
+	cases := []struct {
+		format string
+		input  string
+		err    string
+	}{
+		{
+			format: "json",
+			input:  "{\n  \"a\": 1,\n  \"b\": }\n",
+			err:    "json: line 3:",
+		},
+		{
+			format: "json",
+			input:  "{\"a\": 1} {}",
+			err:    "invalid character after top-level value",
+		},
+		{
+			format: "toml",
+			input:  "a = 1\nb = ]\n",
+			err:    "line 2",
+		},
+		{
+			format: "hcl",
+			input:  "a = 1\nb = var.x\n",
+			err:    "input:2,",
+		},
+		{
+			format: "hcl",
+			input:  "a = 1\nblock {\n}\n",
+			err:    "Unexpected \"block\" block",
+		},
+		{
+			format: "xml",
+			input:  "<a/>",
+			err:    "unknown format 'xml'",
+		},
+	}
+
+	for _, c := range cases {
+		var data map[interface{}]interface{}
+		err := UnmarshalWithFormat(c.format, []byte(c.input), &data, YamlOptions{})
+		if err == nil || !strings.Contains(err.Error(), c.err) {
+			t.Fatalf("Error matching error: %v vs %s", err, c.err)
+		}
+	}
+}
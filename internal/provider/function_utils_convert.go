@@ -0,0 +1,123 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/terraform-plugin-framework/function"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+var _ function.Function = ConvertFunction{}
+
+// formatNames are the names of formats used in descriptions and errors.
+var formatNames = map[string]string{
+	"yaml": "YAML",
+	"json": "JSON",
+	"toml": "TOML",
+}
+
+func NewYamlToJsonFunction() function.Function {
+	return &ConvertFunction{from: "yaml", to: "json"}
+}
+
+func NewJsonToYamlFunction() function.Function {
+	return &ConvertFunction{from: "json", to: "yaml"}
+}
+
+func NewYamlToTomlFunction() function.Function {
+	return &ConvertFunction{from: "yaml", to: "toml"}
+}
+
+func NewTomlToYamlFunction() function.Function {
+	return &ConvertFunction{from: "toml", to: "yaml"}
+}
+
+// ConvertFunction converts strings from one format to another, e.g. the
+// `yaml_to_json` function.
+type ConvertFunction struct {
+	from string
+	to   string
+}
+
+func (r ConvertFunction) Metadata(_ context.Context, req function.MetadataRequest, resp *function.MetadataResponse) {
+	resp.Name = r.from + "_to_" + r.to
+}
+
+func (r ConvertFunction) Definition(_ context.Context, _ function.DefinitionRequest, resp *function.DefinitionResponse) {
+	description := fmt.Sprintf("Convert a %s string to a %s string, where the types of values and the order of keys of the input are kept.", formatNames[r.from], formatNames[r.to])
+	if r.from == "yaml" {
+		description += " YAML `!env` tags can be used to resolve values from environment variables, all other YAML tags of the `utils_yaml_merge` data source are supported as well."
+	}
+	if r.to == "toml" {
+		description += " Null values are omitted as TOML does not support them."
+	}
+	resp.Definition = function.Definition{
+		Summary:             fmt.Sprintf("Convert a %s string to %s", formatNames[r.from], formatNames[r.to]),
+		MarkdownDescription: description,
+		Parameters: []function.Parameter{
+			function.StringParameter{
+				Name:                "input",
+				MarkdownDescription: fmt.Sprintf("A %s string.", formatNames[r.from]),
+			},
+		},
+		VariadicParameter: function.DynamicParameter{
+			Name:                "options",
+			MarkdownDescription: "An object with options: `pretty` to indent JSON output (default `false`), `indent` for the number of spaces used for indentation of JSON and YAML output and `sort_keys` for the order of keys, either `first_seen` to keep the order of the input (default), `alpha` or `none`.",
+		},
+		Return: function.StringReturn{},
+	}
+}
+
+func (r ConvertFunction) Run(ctx context.Context, req function.RunRequest, resp *function.RunResponse) {
+	var input string
+	var options []types.Dynamic
+
+	resp.Error = function.ConcatFuncErrors(req.Arguments.Get(ctx, &input, &options))
+
+	if resp.Error != nil {
+		return
+	}
+
+	if len(options) > 1 {
+		resp.Error = function.NewArgumentFuncError(1, "Invalid options, only a single options object is supported")
+		return
+	}
+	var convertOptions ConvertOptions
+	var err error
+	if len(options) == 1 {
+		var v interface{}
+		v, err = GoValue(ctx, options[0])
+		if err == nil {
+			convertOptions, err = ParseConvertOptions(v)
+		}
+	} else {
+		convertOptions, err = ParseConvertOptions(nil)
+	}
+	if err != nil {
+		resp.Error = function.NewArgumentFuncError(1, "Invalid options: "+err.Error())
+		return
+	}
+
+	styles := NewScalarStyles()
+	var data interface{}
+	err = UnmarshalWithFormat(r.from, []byte(input), &data, YamlOptions{ScalarStyles: styles})
+	if err != nil {
+		resp.Error = function.NewArgumentFuncError(0, fmt.Sprintf("Error reading %s string: %s", formatNames[r.from], err))
+		return
+	}
+
+	data, err = ResolveRefs(data)
+	if err != nil {
+		resp.Error = function.NewArgumentFuncError(0, "Error resolving references: "+err.Error())
+		return
+	}
+
+	output, err := MarshalWithFormat(r.to, data, styles, convertOptions)
+	if err != nil {
+		resp.Error = function.NewFuncError(fmt.Sprintf("Error converting result to %s: %s", formatNames[r.to], err))
+		return
+	}
+
+	resp.Error = function.ConcatFuncErrors(resp.Result.Set(ctx, string(output)))
+}
@@ -0,0 +1,49 @@
+package provider
+
+import (
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+	"github.com/hashicorp/terraform-plugin-testing/tfversion"
+)
+
+func TestConvertFunction_Known(t *testing.T) {
+	resource.UnitTest(t, resource.TestCase{
+		TerraformVersionChecks: []tfversion.TerraformVersionCheck{
+			tfversion.SkipBelow(tfversion.Version1_8_0),
+		},
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: `
+				output "json" {
+					value = provider::utils::yaml_to_json("b: 1.0\na: [x]\n")
+				}
+
+				output "json_pretty" {
+					value = provider::utils::yaml_to_json("b: 1\na: x\n", { pretty = true, sort_keys = "alpha" })
+				}
+
+				output "yaml" {
+					value = provider::utils::json_to_yaml("{\"b\": 1, \"a\": \"x\"}")
+				}
+
+				output "toml" {
+					value = provider::utils::yaml_to_toml("name: test\nowner:\n  name: tom\n")
+				}
+
+				output "toml_yaml" {
+					value = provider::utils::toml_to_yaml("name = \"test\"\n[owner]\nname = \"tom\"\n", { indent = 2 })
+				}
+				`,
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckOutput("json", `{"b":1.0,"a":["x"]}`),
+					resource.TestCheckOutput("json_pretty", "{\n  \"a\": \"x\",\n  \"b\": 1\n}"),
+					resource.TestCheckOutput("yaml", "b: 1\na: x\n"),
+					resource.TestCheckOutput("toml", "name = \"test\"\n\n[owner]\nname = \"tom\"\n"),
+					resource.TestCheckOutput("toml_yaml", "name: test\nowner:\n  name: tom\n"),
+				),
+			},
+		},
+	})
+}
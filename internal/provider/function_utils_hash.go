@@ -0,0 +1,64 @@
+package provider
+
+import (
+	"context"
+
+	"github.com/hashicorp/terraform-plugin-framework/function"
+)
+
+var _ function.Function = HashFunction{}
+
+func NewHashFunction() function.Function {
+	return &HashFunction{}
+}
+
+type HashFunction struct{}
+
+func (r HashFunction) Metadata(_ context.Context, req function.MetadataRequest, resp *function.MetadataResponse) {
+	resp.Name = "hash"
+}
+
+func (r HashFunction) Definition(_ context.Context, _ function.DefinitionRequest, resp *function.DefinitionResponse) {
+	resp.Definition = function.Definition{
+		Summary:             "Hash a YAML string",
+		MarkdownDescription: "Return the hexadecimal encoding of the SHA-256 checksum of the canonical form of a YAML string, where keys are sorted and scalars are normalized. Semantically equal YAML strings have the same hash regardless of their formatting, which is the same as the `id` of the `utils_yaml_merge` data source. YAML `!env` tags can be used to resolve values from environment variables.",
+		Parameters: []function.Parameter{
+			function.StringParameter{
+				Name:                "input",
+				MarkdownDescription: "A YAML string.",
+			},
+		},
+		Return: function.StringReturn{},
+	}
+}
+
+func (r HashFunction) Run(ctx context.Context, req function.RunRequest, resp *function.RunResponse) {
+	var input string
+
+	resp.Error = function.ConcatFuncErrors(req.Arguments.Get(ctx, &input))
+
+	if resp.Error != nil {
+		return
+	}
+
+	var data interface{}
+	err := YamlUnmarshal([]byte(input), &data)
+	if err != nil {
+		resp.Error = function.NewArgumentFuncError(0, "Error reading YAML string: "+err.Error())
+		return
+	}
+
+	data, err = ResolveRefs(data)
+	if err != nil {
+		resp.Error = function.NewArgumentFuncError(0, "Error resolving references: "+err.Error())
+		return
+	}
+
+	hash, err := CanonicalHash(data)
+	if err != nil {
+		resp.Error = function.NewFuncError("Error hashing YAML: " + err.Error())
+		return
+	}
+
+	resp.Error = function.ConcatFuncErrors(resp.Result.Set(ctx, hash))
+}
@@ -0,0 +1,34 @@
+package provider
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+	"github.com/hashicorp/terraform-plugin-testing/tfversion"
+)
+
+func TestHashFunction_Known(t *testing.T) {
+	resource.UnitTest(t, resource.TestCase{
+		TerraformVersionChecks: []tfversion.TerraformVersionCheck{
+			tfversion.SkipBelow(tfversion.Version1_8_0),
+		},
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccFunctionUtilsHash_config("a: 1\nb: [x, y]\n", "b:\n  - 'x'\n  - \"y\"\na: 0x1\n"),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckOutput("equal", "true"),
+				),
+			},
+		},
+	})
+}
+
+func testAccFunctionUtilsHash_config(yaml1, yaml2 string) string {
+	return fmt.Sprintf(`
+	output "equal" {
+		value = provider::utils::hash(%q) == provider::utils::hash(%q)
+	}
+	`, yaml1, yaml2)
+}
@@ -2,11 +2,11 @@ package provider
 
 import (
 	"context"
+	"fmt"
 	"reflect"
 
 	"github.com/hashicorp/terraform-plugin-framework/function"
 	"github.com/hashicorp/terraform-plugin-framework/types"
-	"gopkg.in/yaml.v3"
 )
 
 var _ function.Function = YamlMergeFunction{}
@@ -24,7 +24,7 @@ func (r YamlMergeFunction) Metadata(_ context.Context, req function.MetadataRequ
 func (r YamlMergeFunction) Definition(_ context.Context, _ function.DefinitionRequest, resp *function.DefinitionResponse) {
 	resp.Definition = function.Definition{
 		Summary:             "Merge a list of YAML strings",
-		MarkdownDescription: "Merge a list of YAML strings into a single YAML string, where maps are deep merged and list entries are compared against existing list entries and if all primitive values match, the entries are deep merged. YAML `!env` tags can be used to resolve values from environment variables.",
+		MarkdownDescription: "Merge a list of YAML strings into a single YAML string, where maps are deep merged and list entries are compared against existing list entries and if all primitive values match, the entries are deep merged. YAML `!env` tags can be used to resolve values from environment variables, YAML `!var` tags to resolve values from the `variables` option, YAML `!file`, `!base64`, `!base64file` and `!filehash` tags to embed file content and YAML `!ref` tags to reference values of the merged output, e.g. `!ref path.to.value`. YAML `!if` and `!unless` tags with a `condition` and a `value` key conditionally include map values and list items, where conditions can use environment variables (`env.NAME`) and values of previous inputs (`ref:path.to.value`). YAML `!concat`, `!join`, `!split`, `!upper`, `!lower` and `!format` tags transform values and can be nested, e.g. `!join [\",\", !split [\";\", !env HOSTS]]`. Their arguments cannot be `!ref` values, which are resolved after merging. YAML `!sha256`, `!md5`, `!uuid5` (namespace and name) and `!bcrypt` (password, seed and optional cost) tags compute deterministic hashes and do not accept `!ref` values either. Custom tags of the provider configuration are not supported.",
 		Parameters: []function.Parameter{
 			function.ListParameter{
 				Name:                "input",
@@ -32,28 +32,48 @@ func (r YamlMergeFunction) Definition(_ context.Context, _ function.DefinitionRe
 				MarkdownDescription: "A list of YAML strings that is merged.",
 			},
 		},
+		VariadicParameter: function.DynamicParameter{
+			Name:                "options",
+			MarkdownDescription: "An object with options: `variables`, a map of variables resolved by YAML `!var` tags, `env_from_variables` to resolve YAML `!env` tags from `variables` instead of the environment (default `false`) and `env_files`, a list of `.env` or Java `.properties` files with variables resolved by YAML `!env` tags, which take precedence over the environment.",
+		},
 		Return: function.StringReturn{},
 	}
 }
 
 func (r YamlMergeFunction) Run(ctx context.Context, req function.RunRequest, resp *function.RunResponse) {
 	var input []string
+	var arguments []types.Dynamic
 
-	resp.Error = function.ConcatFuncErrors(req.Arguments.Get(ctx, &input))
+	resp.Error = function.ConcatFuncErrors(req.Arguments.Get(ctx, &input, &arguments))
 
 	if resp.Error != nil {
 		return
 	}
 
+	if len(arguments) > 1 {
+		resp.Error = function.NewArgumentFuncError(1, "Invalid options, only a single options object is supported")
+		return
+	}
 	merged := map[interface{}]interface{}{}
 	vMerged := reflect.ValueOf(merged)
+	options := YamlOptions{Refs: merged, ScalarStyles: NewScalarStyles()}
+	if len(arguments) == 1 {
+		v, err := GoValue(ctx, arguments[0])
+		if err == nil {
+			err = parseYamlMergeOptions(v, &options)
+		}
+		if err != nil {
+			resp.Error = function.NewArgumentFuncError(1, "Invalid options: "+err.Error())
+			return
+		}
+	}
 	for _, input := range input {
 		var data map[interface{}]interface{}
 		b := []byte(input)
 
-		err := YamlUnmarshal(b, &data)
+		err := YamlUnmarshalWithOptions(b, &data, options)
 		if err != nil {
-			function.ConcatFuncErrors(resp.Error, function.NewFuncError("Error reading YAML string: "+err.Error()))
+			resp.Error = function.NewFuncError("Error reading YAML string: " + err.Error())
 			return
 		}
 
@@ -61,16 +81,71 @@ func (r YamlMergeFunction) Run(ctx context.Context, req function.RunRequest, res
 
 		err = MergeMaps(vMerged, vData, true)
 		if err != nil {
-			function.ConcatFuncErrors(resp.Error, function.NewFuncError("Error merging YAML: "+err.Error()))
+			resp.Error = function.NewFuncError("Error merging YAML: " + err.Error())
 			return
 		}
 	}
 
-	output, err := yaml.Marshal(merged)
+	_, err := ResolveRefs(merged)
 	if err != nil {
-		function.ConcatFuncErrors(resp.Error, function.NewFuncError("Error converting results to YAML: "+err.Error()))
+		resp.Error = function.NewFuncError("Error resolving references: " + err.Error())
+		return
+	}
+
+	output, err := YamlMarshal(merged, options.ScalarStyles)
+	if err != nil {
+		resp.Error = function.NewFuncError("Error converting results to YAML: " + err.Error())
 		return
 	}
 
 	resp.Error = function.ConcatFuncErrors(resp.Result.Set(ctx, string(output)))
 }
+
+// parseYamlMergeOptions reads the options of the `yaml_merge` function from
+// a map with the optional keys `variables`, `env_from_variables` and
+// `env_files`.
+func parseYamlMergeOptions(v interface{}, options *YamlOptions) error {
+	if v == nil {
+		return nil
+	}
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("options must be an object")
+	}
+	for key, value := range m {
+		var valid bool
+		switch key {
+		case "variables":
+			var variables map[string]interface{}
+			variables, valid = value.(map[string]interface{})
+			options.Variables = map[string]string{}
+			for name, variable := range variables {
+				options.Variables[name], ok = variable.(string)
+				valid = valid && ok
+			}
+		case "env_from_variables":
+			options.EnvFromVariables, valid = value.(bool)
+		case "env_files":
+			var files []interface{}
+			files, valid = value.([]interface{})
+			paths := make([]string, len(files))
+			for j, file := range files {
+				paths[j], ok = file.(string)
+				valid = valid && ok
+			}
+			if valid {
+				vars, err := LoadEnvFiles(paths)
+				if err != nil {
+					return fmt.Errorf("error reading env files: %s", err)
+				}
+				options.Env = vars
+			}
+		default:
+			return fmt.Errorf("unknown option '%s'", key)
+		}
+		if !valid {
+			return fmt.Errorf("invalid value of option '%s'", key)
+		}
+	}
+	return nil
+}
@@ -1,10 +1,18 @@
 package provider
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
 	"testing"
 
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/function"
+	"github.com/hashicorp/terraform-plugin-framework/types"
 	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
 	"github.com/hashicorp/terraform-plugin-testing/tfversion"
 )
@@ -26,10 +34,71 @@ func TestYamlMergeFunction_Known(t *testing.T) {
 	})
 }
 
-func testAccFucntionUtilsYamlMerge_config(yaml1, yaml2 string, envs map[string]string) string {
-	for k, v := range envs {
-		os.Setenv(k, v)
+func TestYamlMergeFunction_Error(t *testing.T) {
+	resource.UnitTest(t, resource.TestCase{
+		TerraformVersionChecks: []tfversion.TerraformVersionCheck{
+			tfversion.SkipBelow(tfversion.Version1_8_0),
+		},
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config:      testAccFucntionUtilsYamlMerge_config("\na: !var MISSING\n", "\nb: 2\n", map[string]string{}),
+				ExpectError: regexp.MustCompile(`variable MISSING not set`),
+			},
+		},
+	})
+}
+
+// Errors of reading, merging and converting inputs are returned by the
+// function instead of an unknown result.
+func TestYamlMergeRunErrors(t *testing.T) {
+	cases := []struct {
+		input   string
+		options map[string]attr.Value
+		err     string
+	}{
+		{
+			input:   "a: !var MISSING\n",
+			options: map[string]attr.Value{"variables": types.MapValueMust(types.StringType, map[string]attr.Value{})},
+			err:     "Error reading YAML string: variable MISSING not set",
+		},
+		{
+			input: "a: [\n",
+			err:   "Error reading YAML string",
+		},
+		{
+			input: "a: !ref missing\n",
+			err:   "Error resolving references",
+		},
+	}
+
+	ctx := context.Background()
+	for _, c := range cases {
+		options := []attr.Value{}
+		optionTypes := []attr.Type{}
+		if c.options != nil {
+			attributeTypes := map[string]attr.Type{}
+			for name, value := range c.options {
+				attributeTypes[name] = value.Type(ctx)
+			}
+			options = append(options, types.DynamicValue(types.ObjectValueMust(attributeTypes, c.options)))
+			optionTypes = append(optionTypes, types.DynamicType)
+		}
+		req := function.RunRequest{
+			Arguments: function.NewArgumentsData([]attr.Value{
+				types.ListValueMust(types.StringType, []attr.Value{types.StringValue(c.input)}),
+				types.TupleValueMust(optionTypes, options),
+			}),
+		}
+		resp := &function.RunResponse{Result: function.NewResultData(types.StringUnknown())}
+		NewYamlMergeFunction().Run(ctx, req, resp)
+		if resp.Error == nil || !strings.Contains(resp.Error.Error(), c.err) {
+			t.Fatalf("Error matching error: %v vs %s", resp.Error, c.err)
+		}
 	}
+}
+
+func testAccFucntionUtilsYamlMerge_config(yaml1, yaml2 string, variables map[string]string) string {
 	return fmt.Sprintf(`
 	locals {
 		yaml1 = <<-EOT%sEOT
@@ -37,7 +106,76 @@ func testAccFucntionUtilsYamlMerge_config(yaml1, yaml2 string, envs map[string]s
 	}
 
 	output "test" {
-		value = provider::utils::yaml_merge([local.yaml1, local.yaml2])
+		value = provider::utils::yaml_merge([local.yaml1, local.yaml2], {variables = %s})
+	}
+	`, yaml1, yaml2, testAccVariables(variables))
+}
+
+func TestParseYamlMergeOptions(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), ".env")
+	os.WriteFile(filename, []byte("NAME=dotenv\n"), 0644)
+
+	cases := []struct {
+		input  interface{}
+		result YamlOptions
+		err    string
+	}{
+		{
+			input:  nil,
+			result: YamlOptions{},
+		},
+		{
+			input: map[string]interface{}{
+				"variables":          map[string]interface{}{"NAME": "value1"},
+				"env_from_variables": true,
+				"env_files":          []interface{}{filename},
+			},
+			result: YamlOptions{
+				Variables:        map[string]string{"NAME": "value1"},
+				EnvFromVariables: true,
+				Env:              map[string]string{"NAME": "dotenv"},
+			},
+		},
+		{
+			input: "options",
+			err:   "options must be an object",
+		},
+		{
+			input: map[string]interface{}{"other": true},
+			err:   "unknown option 'other'",
+		},
+		{
+			input: map[string]interface{}{"variables": map[string]interface{}{"NAME": int64(1)}},
+			err:   "invalid value of option 'variables'",
+		},
+		{
+			input: map[string]interface{}{"env_from_variables": "true"},
+			err:   "invalid value of option 'env_from_variables'",
+		},
+		{
+			input: map[string]interface{}{"env_files": filename},
+			err:   "invalid value of option 'env_files'",
+		},
+		{
+			input: map[string]interface{}{"env_files": []interface{}{filename + ".missing"}},
+			err:   "error reading env files",
+		},
+	}
+
+	for _, c := range cases {
+		var options YamlOptions
+		err := parseYamlMergeOptions(c.input, &options)
+		if c.err != "" {
+			if err == nil || !strings.Contains(err.Error(), c.err) {
+				t.Fatalf("Error matching error: %v vs %s", err, c.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("Error parsing options: %s", err)
+		}
+		if !reflect.DeepEqual(options, c.result) {
+			t.Fatalf("Error matching options and result: %#v vs %#v", options, c.result)
+		}
 	}
-	`, yaml1, yaml2)
 }
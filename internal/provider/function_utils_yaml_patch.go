@@ -0,0 +1,106 @@
+package provider
+
+import (
+	"context"
+	"reflect"
+
+	"github.com/hashicorp/terraform-plugin-framework/function"
+	"gopkg.in/yaml.v3"
+)
+
+var _ function.Function = YamlPatchFunction{}
+
+func NewYamlPatchFunction() function.Function {
+	return &YamlPatchFunction{}
+}
+
+type YamlPatchFunction struct{}
+
+func (r YamlPatchFunction) Metadata(_ context.Context, req function.MetadataRequest, resp *function.MetadataResponse) {
+	resp.Name = "yaml_patch"
+}
+
+func (r YamlPatchFunction) Definition(_ context.Context, _ function.DefinitionRequest, resp *function.DefinitionResponse) {
+	resp.Definition = function.Definition{
+		Summary:             "Patch a YAML string",
+		MarkdownDescription: "Apply a JSON Patch (RFC 6902) or a JSON Merge Patch (RFC 7386) to a YAML string and return the result as YAML string. A patch which is a list of operations is applied as JSON Patch, any other patch as JSON Merge Patch. YAML `!env` tags can be used to resolve values from environment variables.",
+		Parameters: []function.Parameter{
+			function.StringParameter{
+				Name:                "input",
+				MarkdownDescription: "A YAML string that is patched.",
+			},
+			function.StringParameter{
+				Name:                "patch",
+				MarkdownDescription: "A YAML or JSON string with the patch.",
+			},
+		},
+		VariadicParameter: function.StringParameter{
+			Name:                "format",
+			MarkdownDescription: "The format of the patch, either `json_patch` or `merge_patch`. By default the format is detected from the patch.",
+		},
+		Return: function.StringReturn{},
+	}
+}
+
+func (r YamlPatchFunction) Run(ctx context.Context, req function.RunRequest, resp *function.RunResponse) {
+	var input, patch string
+	var format []string
+
+	resp.Error = function.ConcatFuncErrors(req.Arguments.Get(ctx, &input, &patch, &format))
+
+	if resp.Error != nil {
+		return
+	}
+
+	if len(format) > 1 || len(format) == 1 && format[0] != "json_patch" && format[0] != "merge_patch" {
+		resp.Error = function.NewArgumentFuncError(2, "Invalid format, must be one of `json_patch` or `merge_patch`")
+		return
+	}
+
+	var data, patchData interface{}
+	err := YamlUnmarshal([]byte(input), &data)
+	if err != nil {
+		resp.Error = function.NewArgumentFuncError(0, "Error reading YAML string: "+err.Error())
+		return
+	}
+	err = YamlUnmarshal([]byte(patch), &patchData)
+	if err != nil {
+		resp.Error = function.NewArgumentFuncError(1, "Error reading patch: "+err.Error())
+		return
+	}
+
+	jsonPatch := reflect.ValueOf(patchData).Kind() == reflect.Slice
+	if len(format) == 1 {
+		jsonPatch = format[0] == "json_patch"
+	}
+
+	var patched interface{}
+	if jsonPatch {
+		operations, ok := patchData.([]interface{})
+		if !ok {
+			resp.Error = function.NewArgumentFuncError(1, "Error reading patch: JSON Patch must be a list of operations")
+			return
+		}
+		patched, err = ApplyJsonPatch(data, operations)
+		if err != nil {
+			resp.Error = function.NewFuncError("Error applying patch: " + err.Error())
+			return
+		}
+	} else {
+		patched = ApplyJsonMergePatch(data, patchData)
+	}
+
+	patched, err = ResolveRefs(patched)
+	if err != nil {
+		resp.Error = function.NewFuncError("Error resolving references: " + err.Error())
+		return
+	}
+
+	output, err := yaml.Marshal(patched)
+	if err != nil {
+		resp.Error = function.NewFuncError("Error converting results to YAML: " + err.Error())
+		return
+	}
+
+	resp.Error = function.ConcatFuncErrors(resp.Result.Set(ctx, string(output)))
+}
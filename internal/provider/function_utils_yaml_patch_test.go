@@ -0,0 +1,85 @@
+package provider
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+	"github.com/hashicorp/terraform-plugin-testing/tfversion"
+)
+
+func TestYamlPatchFunction_Known(t *testing.T) {
+	resource.UnitTest(t, resource.TestCase{
+		TerraformVersionChecks: []tfversion.TerraformVersionCheck{
+			tfversion.SkipBelow(tfversion.Version1_8_0),
+		},
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccFunctionUtilsYamlPatch_config(patch_inputYaml, patch_jsonPatchYaml),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckOutput("test", patch_jsonPatchOutputYaml),
+				),
+			},
+			{
+				Config: testAccFunctionUtilsYamlPatch_config(patch_inputYaml, patch_mergePatchYaml),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckOutput("test", patch_mergePatchOutputYaml),
+				),
+			},
+		},
+	})
+}
+
+func testAccFunctionUtilsYamlPatch_config(yaml, patch string) string {
+	return fmt.Sprintf(`
+	locals {
+		yaml  = <<-EOT%sEOT
+		patch = <<-EOT%sEOT
+	}
+
+	output "test" {
+		value = provider::utils::yaml_patch(local.yaml, local.patch)
+	}
+	`, yaml, patch)
+}
+
+const patch_inputYaml = `
+root:
+  elem1: value1
+  elem2: value2
+list:
+  - a1
+  - a2
+`
+
+const patch_jsonPatchYaml = `
+- op: test
+  path: /root/elem1
+  value: value1
+- op: remove
+  path: /list/0
+- op: replace
+  path: /root/elem2
+  value: value3
+`
+
+const patch_jsonPatchOutputYaml = `list:
+    - a2
+root:
+    elem1: value1
+    elem2: value3
+`
+
+const patch_mergePatchYaml = `
+root:
+  elem1: null
+list:
+  - a3
+`
+
+const patch_mergePatchOutputYaml = `list:
+    - a3
+root:
+    elem2: value2
+`
@@ -0,0 +1,107 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/function"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"gopkg.in/yaml.v3"
+)
+
+var _ function.Function = YamlQueryFunction{}
+
+func NewYamlQueryFunction() function.Function {
+	return &YamlQueryFunction{}
+}
+
+type YamlQueryFunction struct{}
+
+func (r YamlQueryFunction) Metadata(_ context.Context, req function.MetadataRequest, resp *function.MetadataResponse) {
+	resp.Name = "yaml_query"
+}
+
+func (r YamlQueryFunction) Definition(_ context.Context, _ function.DefinitionRequest, resp *function.DefinitionResponse) {
+	resp.Definition = function.Definition{
+		Summary:             "Query a YAML string",
+		MarkdownDescription: "Evaluate a jq/yq style path expression, e.g. `.tenants[] | select(.name == \"prod\") | .vrfs`, against a YAML string and return a list of all matching nodes. YAML `!env` tags can be used to resolve values from environment variables.",
+		Parameters: []function.Parameter{
+			function.StringParameter{
+				Name:                "input",
+				MarkdownDescription: "A YAML string.",
+			},
+			function.StringParameter{
+				Name:                "query",
+				MarkdownDescription: "A path expression supporting field access (`.a`), indexing (`.[0]`), iteration (`.[]`), pipes (`|`), `select()`, `has()`, `not`, `length`, `keys`, comparisons and `and`/`or`.",
+			},
+		},
+		VariadicParameter: function.StringParameter{
+			Name:                "format",
+			MarkdownDescription: "The format of the result, either `dynamic` to return a list of values or `yaml` to return a YAML string. Default value is `dynamic`.",
+		},
+		Return: function.DynamicReturn{},
+	}
+}
+
+func (r YamlQueryFunction) Run(ctx context.Context, req function.RunRequest, resp *function.RunResponse) {
+	var input, query string
+	var format []string
+
+	resp.Error = function.ConcatFuncErrors(req.Arguments.Get(ctx, &input, &query, &format))
+
+	if resp.Error != nil {
+		return
+	}
+
+	if len(format) > 1 || len(format) == 1 && format[0] != "dynamic" && format[0] != "yaml" {
+		resp.Error = function.NewArgumentFuncError(2, "Invalid format, must be one of `dynamic` or `yaml`")
+		return
+	}
+
+	q, err := ParseQuery(query)
+	if err != nil {
+		resp.Error = function.NewArgumentFuncError(1, "Error parsing query: "+err.Error())
+		return
+	}
+
+	var data interface{}
+	err = YamlUnmarshal([]byte(input), &data)
+	if err != nil {
+		resp.Error = function.NewArgumentFuncError(0, "Error reading YAML string: "+err.Error())
+		return
+	}
+
+	data, err = ResolveRefs(data)
+	if err != nil {
+		resp.Error = function.NewArgumentFuncError(0, "Error resolving references: "+err.Error())
+		return
+	}
+
+	results, err := q.Run(data)
+	if err != nil {
+		resp.Error = function.NewFuncError("Error evaluating query: " + err.Error())
+		return
+	}
+	if results == nil {
+		results = []interface{}{}
+	}
+
+	var value attr.Value
+	if len(format) == 1 && format[0] == "yaml" {
+		output, err := yaml.Marshal(results)
+		if err != nil {
+			resp.Error = function.NewFuncError("Error converting results to YAML: " + err.Error())
+			return
+		}
+		value = types.StringValue(string(output))
+	} else {
+		value, err = DynamicValue(ctx, results)
+		if err != nil {
+			resp.Error = function.NewFuncError(fmt.Sprintf("Error converting results: %s", err))
+			return
+		}
+	}
+
+	resp.Error = function.ConcatFuncErrors(resp.Result.Set(ctx, types.DynamicValue(value)))
+}
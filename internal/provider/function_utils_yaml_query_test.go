@@ -0,0 +1,54 @@
+package provider
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+	"github.com/hashicorp/terraform-plugin-testing/tfversion"
+)
+
+func TestYamlQueryFunction_Known(t *testing.T) {
+	resource.UnitTest(t, resource.TestCase{
+		TerraformVersionChecks: []tfversion.TerraformVersionCheck{
+			tfversion.SkipBelow(tfversion.Version1_8_0),
+		},
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccFunctionUtilsYamlQuery_config(query_inputYaml, `.tenants[] | select(.name == \"prod\") | .vrfs[].name`),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckOutput("dynamic", "v1,v2"),
+					resource.TestCheckOutput("yaml", "- v1\n- v2\n"),
+				),
+			},
+		},
+	})
+}
+
+func testAccFunctionUtilsYamlQuery_config(yaml, query string) string {
+	return fmt.Sprintf(`
+	locals {
+		yaml = <<-EOT%sEOT
+	}
+
+	output "dynamic" {
+		value = join(",", provider::utils::yaml_query(local.yaml, "%[2]s"))
+	}
+
+	output "yaml" {
+		value = provider::utils::yaml_query(local.yaml, "%[2]s", "yaml")
+	}
+	`, yaml, query)
+}
+
+const query_inputYaml = `
+tenants:
+  - name: prod
+    vrfs:
+      - name: v1
+      - name: v2
+  - name: dev
+    vrfs:
+      - name: v3
+`
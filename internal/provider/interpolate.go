@@ -0,0 +1,87 @@
+package provider
+
+import (
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// interpolation is the value of a string with `${ref:path}` expressions
+// until it is resolved by ResolveRefs.
+type interpolation struct {
+	// location of the string used in error messages
+	location string
+	template string
+}
+
+// interpolate replaces `${env.NAME}` expressions in a string scalar. Strings
+// with `${ref:path}` expressions are replaced with a marker, which is resolved
+// after merging.
+func (i *CustomTagProcessor) interpolate(node *yaml.Node) error {
+	if node.Kind != yaml.ScalarNode || node.ShortTag() != "!!str" || !strings.Contains(node.Value, "${") {
+		return nil
+	}
+	location := fmt.Sprintf("line %d", node.Line)
+	if i.options.Name != "" {
+		location = i.options.Name + " " + location
+	}
+	deferred := false
+	template, err := interpolateString(node.Value, true, func(expr string) (string, error) {
+		if strings.HasPrefix(expr, "env.") {
+			value := i.getenv(expr[4:])
+			if value == "" {
+				return "", fmt.Errorf("environment variable %v not set", expr[4:])
+			}
+			return strings.ReplaceAll(value, "${", "$${"), nil
+		}
+		if strings.HasPrefix(expr, "ref:") && len(expr) > 4 {
+			deferred = true
+			return "${" + expr + "}", nil
+		}
+		return "", fmt.Errorf("unknown expression '${%s}'", expr)
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %s", location, err)
+	}
+	if deferred {
+		// line numbers of immediate errors are corrected by YamlUnmarshalWithOptions
+		location = mapErrorLines(location, i.sourceLine)
+		i.mark(node, interpolation{location: location, template: template})
+		return nil
+	}
+	node.Value, err = interpolateString(template, false, nil)
+	return err
+}
+
+// interpolateString replaces all `${expr}` expressions in s with the result
+// of fn. Escaped expressions (`$${`) are kept escaped if keepEscapes is set.
+func interpolateString(s string, keepEscapes bool, fn func(expr string) (string, error)) (string, error) {
+	var b strings.Builder
+	for len(s) > 0 {
+		switch {
+		case strings.HasPrefix(s, "$${"):
+			if keepEscapes {
+				b.WriteString("$${")
+			} else {
+				b.WriteString("${")
+			}
+			s = s[3:]
+		case strings.HasPrefix(s, "${"):
+			end := strings.Index(s, "}")
+			if end < 0 {
+				return "", fmt.Errorf("unterminated expression '%s'", s)
+			}
+			value, err := fn(strings.TrimSpace(s[2:end]))
+			if err != nil {
+				return "", err
+			}
+			b.WriteString(value)
+			s = s[end+1:]
+		default:
+			b.WriteByte(s[0])
+			s = s[1:]
+		}
+	}
+	return b.String(), nil
+}
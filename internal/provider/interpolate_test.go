@@ -0,0 +1,117 @@
+package provider
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestInterpolate(t *testing.T) {
+	t.Setenv("YAML_TEST_HOST", "host1")
+	t.Setenv("YAML_TEST_TEMPLATE", "${env.YAML_TEST_HOST}")
+
+	cases := []struct {
+		input  string
+		result map[string]interface{}
+	}{
+		// environment variables
+		{
+			input: "url: https://${env.YAML_TEST_HOST}:8443/api\n",
+			result: map[string]interface{}{
+				"url": "https://host1:8443/api",
+			},
+		},
+		// references
+		{
+			input: "tenant: tenant1\nname: ${ref:tenant}-${ env.YAML_TEST_HOST }\nlist:\n  - x-${ref:tenant}\n",
+			result: map[string]interface{}{
+				"tenant": "tenant1",
+				"name":   "tenant1-host1",
+				"list":   []interface{}{"x-tenant1"},
+			},
+		},
+		// escaping
+		{
+			input: "elem1: $${env.YAML_TEST_HOST}\nelem2: $${ref:elem1} ${ref:elem3}\nelem3: a$b\n",
+			result: map[string]interface{}{
+				"elem1": "${env.YAML_TEST_HOST}",
+				"elem2": "${ref:elem1} a$b",
+				"elem3": "a$b",
+			},
+		},
+		// keys and values of other tags are not interpolated
+		{
+			input: "${env.YAML_TEST_HOST}: 1\nelem1: !env YAML_TEST_TEMPLATE\n",
+			result: map[string]interface{}{
+				"${env.YAML_TEST_HOST}": 1,
+				"elem1":                 "${env.YAML_TEST_HOST}",
+			},
+		},
+		// strings looking like deferred interpolations are regular strings
+		{
+			input: "elem1: \"\\0!interpolate:line 1\\0${ref:elem2}\"\nelem2: 1\n",
+			result: map[string]interface{}{
+				"elem1": "\x00!interpolate:line 1\x001",
+				"elem2": 1,
+			},
+		},
+	}
+
+	for _, c := range cases {
+		var data map[string]interface{}
+		err := YamlUnmarshalWithOptions([]byte(c.input), &data, YamlOptions{Interpolate: true})
+		if err != nil {
+			t.Fatalf("Error reading YAML string: %s", err)
+		}
+		_, err = ResolveRefs(data)
+		if err != nil {
+			t.Fatalf("Error resolving references: %s", err)
+		}
+		if !reflect.DeepEqual(data, c.result) {
+			t.Fatalf("Error matching data and result: %#v vs %#v", data, c.result)
+		}
+	}
+}
+
+func TestInterpolateErrors(t *testing.T) {
+	cases := []struct {
+		input string
+		err   string
+	}{
+		{
+			input: "elem1: value\nelem2: ${env.YAML_TEST_MISSING}\n",
+			err:   "input[1] line 2: environment variable YAML_TEST_MISSING not set",
+		},
+		{
+			input: "elem1: ${unknown}\n",
+			err:   "input[1] line 1: unknown expression '${unknown}'",
+		},
+		{
+			input: "elem1: ${env.YAML_TEST_HOST\n",
+			err:   "input[1] line 1: unterminated expression",
+		},
+		{
+			input: "elem1: value\nelem2: ${ref:missing}\n",
+			err:   "input[1] line 2: !ref missing: path 'missing' does not exist",
+		},
+		{
+			input: "elem1: ${ref:elem2}\nelem2: {a: b}\n",
+			err:   "input[1] line 1: ${ref:elem2} is not a scalar value",
+		},
+		{
+			input: "elem1: ${ref:elem1}\n",
+			err:   "reference cycle",
+		},
+	}
+
+	for _, c := range cases {
+		var data map[string]interface{}
+		err := YamlUnmarshalWithOptions([]byte(c.input), &data, YamlOptions{Interpolate: true, Name: "input[1]"})
+		if err == nil {
+			_, err = ResolveRefs(data)
+		}
+		if err == nil || !strings.Contains(err.Error(), c.err) {
+			t.Fatalf("Error matching error: %v vs %s", err, c.err)
+		}
+	}
+}
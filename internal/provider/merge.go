@@ -3,13 +3,42 @@ package provider
 import (
 	"fmt"
 	"reflect"
+	"strings"
 )
 
+// MergeOptions controls how maps and list items are merged.
+type MergeOptions struct {
+	// MergeListItems deep merges list items if all primitive values match.
+	MergeListItems bool
+	// Strategic enables the strategic merge patch directives `$patch`
+	// (`replace`, `delete` or `merge`), `$retainKeys` and
+	// `$setElementOrder/<list>`. Directive keys are never added to dst.
+	Strategic bool
+	// MergeKey is the key used to match list items, if set, list items with
+	// the same value for this key are deep merged.
+	MergeKey string
+}
+
 func MergeMaps(dst, src reflect.Value, mergeListItems bool) error {
+	return MergeMapsWithOptions(dst, src, MergeOptions{MergeListItems: mergeListItems})
+}
+
+func MergeMapsWithOptions(dst, src reflect.Value, opts MergeOptions) error {
 
 	if dst.Kind() != reflect.Map || src.Kind() != reflect.Map {
 		return fmt.Errorf("[ERROR] src and/or dst in MergeMaps not a Map.")
 	}
+	if opts.Strategic {
+		switch patchDirective(src) {
+		case "replace", "delete":
+			for _, key := range dst.MapKeys() {
+				dst.SetMapIndex(key, reflect.Value{})
+			}
+			if patchDirective(src) == "delete" {
+				return nil
+			}
+		}
+	}
 	// iterate over source map keys and values
 	iter := src.MapRange()
 	for iter.Next() {
@@ -17,12 +46,20 @@ func MergeMaps(dst, src reflect.Value, mergeListItems bool) error {
 		if sKey.Kind() == reflect.Interface {
 			sKey = sKey.Elem()
 		}
+		if opts.Strategic && isDirectiveKey(sKey) {
+			continue
+		}
 		sValue := iter.Value()
 		if sValue.Kind() == reflect.Interface {
 			sValue = sValue.Elem()
 		}
 
 		if sValue.Kind() == reflect.Map {
+			// remove key from dst
+			if opts.Strategic && patchDirective(sValue) == "delete" {
+				dst.SetMapIndex(sKey, reflect.Value{})
+				continue
+			}
 			dValue := dst.MapIndex(sKey)
 			// add empty map to dst if key does not exist
 			if !dValue.IsValid() {
@@ -30,18 +67,27 @@ func MergeMaps(dst, src reflect.Value, mergeListItems bool) error {
 			}
 			// merge src map into dst map
 			dValue = reflect.ValueOf(dst.MapIndex(sKey).Interface())
-			MergeMaps(dValue, sValue, mergeListItems)
+			err := MergeMapsWithOptions(dValue, sValue, opts)
+			if err != nil {
+				return err
+			}
 		} else if sValue.Kind() == reflect.Slice {
 			dValue := dst.MapIndex(sKey)
-			// if slice does not exist in dst, add empty slice
-			if !dValue.IsValid() {
+			// if slice does not exist in dst or should be replaced, add empty slice
+			if !dValue.IsValid() || opts.Strategic && hasListReplaceDirective(sValue) {
 				dst.SetMapIndex(sKey, reflect.MakeSlice(sValue.Type(), 0, 0))
 			}
 			dValue = reflect.ValueOf(dst.MapIndex(sKey).Interface())
 			if dValue.Kind() == reflect.Slice {
 				// iterate over source slice elements and add merge with dst list
 				for i := 0; i < sValue.Len(); i++ {
-					MergeListItem(dst, sKey, sValue.Index(i), mergeListItems)
+					if opts.Strategic && listDirective(sValue.Index(i)) == "replace" {
+						continue
+					}
+					err := MergeListItemWithOptions(dst, sKey, sValue.Index(i), opts)
+					if err != nil {
+						return err
+					}
 				}
 			}
 		} else {
@@ -51,103 +97,398 @@ func MergeMaps(dst, src reflect.Value, mergeListItems bool) error {
 			}
 		}
 	}
+	if opts.Strategic {
+		applyRetainKeys(dst, src)
+		applySetElementOrder(dst, src, opts)
+	}
 	return nil
 }
 
-func MergeListItem(dst, key, src reflect.Value, mergeListItems bool) {
+func MergeListItem(dst, key, src reflect.Value, mergeListItems bool) error {
+	return MergeListItemWithOptions(dst, key, src, MergeOptions{MergeListItems: mergeListItems})
+}
+
+func MergeListItemWithOptions(dst, key, src reflect.Value, opts MergeOptions) error {
 	dValue := reflect.ValueOf(dst.MapIndex(key).Interface())
 	if src.Kind() == reflect.Interface {
 		src = src.Elem()
 	}
-	if src.Kind() == reflect.Map && mergeListItems {
-		for i := 0; i < dValue.Len(); i++ {
-			match := true
-			comparison := false
-			uniqueSource := false
-			uniqueDest := false
-			// iterate over all source map keys and values
-			iter := src.MapRange()
-			for iter.Next() {
-				sKey := iter.Key()
-				if sKey.Kind() == reflect.Interface {
-					sKey = sKey.Elem()
-				}
-				sValue := iter.Value()
-				if sValue.Kind() == reflect.Interface {
-					sValue = sValue.Elem()
-				}
+	if src.Kind() == reflect.Map && (opts.MergeListItems || hasMergeKey(src, opts)) {
+		i := matchListItem(dValue, src, opts)
+		if i >= 0 {
+			switch {
+			case opts.Strategic && patchDirective(src) == "delete":
+				dst.SetMapIndex(key, reflect.AppendSlice(dValue.Slice(0, i), dValue.Slice(i+1, dValue.Len())))
+			case opts.Strategic && patchDirective(src) == "replace":
+				dValue.Index(i).Set(reflect.ValueOf(stripDirectives(src.Interface())))
+			default:
+				dv := reflect.ValueOf(dst.MapIndex(key).Elem().Index(i).Interface())
+				return MergeMapsWithOptions(dv, src, opts)
+			}
+			return nil
+		}
 
-				if sValue.Kind() == reflect.Map || sValue.Kind() == reflect.Slice {
-					// we only compare primitive types
-					continue
-				}
-				x := dValue.Index(i).Elem().MapIndex(sKey)
-				if x.Kind() == reflect.Interface {
-					x = x.Elem()
-				}
-				// check if element exists in dst map and value is the same as in src map
-				if x.IsValid() && sValue.IsValid() && sValue.Interface() == x.Interface() {
-					comparison = true
-					continue
-				}
-				// if value does not exist in dst map -> continue
-				if !x.IsValid() && sValue.IsValid() {
-					uniqueSource = true
-					continue
-				}
+	} else {
+		// check if primitive value exists in dst and if so return
+		slice := dst.MapIndex(key).Elem()
+		for i := 0; i < slice.Len(); i++ {
+			element := slice.Index(i).Elem()
+			if element.IsValid() && src.IsValid() && element.Kind() != reflect.Map && element.Kind() != reflect.Slice && element.Interface() == src.Interface() {
+				return nil
+			}
+		}
+	}
+	if opts.Strategic && src.IsValid() {
+		// nothing to delete
+		if patchDirective(src) == "delete" {
+			return nil
+		}
+		src = reflectValue(stripDirectives(src.Interface()), dValue.Type().Elem())
+	}
+	dst.SetMapIndex(key, reflect.Append(dValue, src))
+	return nil
+}
+
+// matchListItem returns the index of the first item in list matching the
+// src map or -1. Items match if they have the same value for the merge key or,
+// if no merge key is configured, if all primitive values match.
+func matchListItem(list, src reflect.Value, opts MergeOptions) int {
+	if hasMergeKey(src, opts) {
+		value := src.MapIndex(reflect.ValueOf(opts.MergeKey))
+		for i := 0; i < list.Len(); i++ {
+			item := list.Index(i).Elem()
+			if item.Kind() == reflect.Map && mapHasKey(item, opts.MergeKey) && jsonEqual(mapIndex(item, opts.MergeKey), value.Interface()) {
+				return i
+			}
+		}
+		return -1
+	}
+	for i := 0; i < list.Len(); i++ {
+		match := true
+		comparison := false
+		uniqueSource := false
+		uniqueDest := false
+		// iterate over all source map keys and values
+		iter := src.MapRange()
+		for iter.Next() {
+			sKey := iter.Key()
+			if sKey.Kind() == reflect.Interface {
+				sKey = sKey.Elem()
+			}
+			if opts.Strategic && isDirectiveKey(sKey) {
+				continue
+			}
+			sValue := iter.Value()
+			if sValue.Kind() == reflect.Interface {
+				sValue = sValue.Elem()
+			}
+
+			if sValue.Kind() == reflect.Map || sValue.Kind() == reflect.Slice {
+				// we only compare primitive types
+				continue
+			}
+			x := list.Index(i).Elem().MapIndex(sKey)
+			if x.Kind() == reflect.Interface {
+				x = x.Elem()
+			}
+			// check if element exists in dst map and value is the same as in src map
+			if x.IsValid() && sValue.IsValid() && sValue.Interface() == x.Interface() {
 				comparison = true
-				match = false
-			}
-			// iterate over all dst map keys and values
-			iter = dValue.Index(i).Elem().MapRange()
-			for iter.Next() {
-				dKey := iter.Key()
-				if dKey.Kind() == reflect.Interface {
-					dKey = dKey.Elem()
-				}
-				dValue := iter.Value()
-				if dValue.Kind() == reflect.Interface {
-					dValue = dValue.Elem()
-				}
+				continue
+			}
+			// if value does not exist in dst map -> continue
+			if !x.IsValid() && sValue.IsValid() {
+				uniqueSource = true
+				continue
+			}
+			comparison = true
+			match = false
+		}
+		// iterate over all dst map keys and values
+		iter = list.Index(i).Elem().MapRange()
+		for iter.Next() {
+			dKey := iter.Key()
+			if dKey.Kind() == reflect.Interface {
+				dKey = dKey.Elem()
+			}
+			dValue := iter.Value()
+			if dValue.Kind() == reflect.Interface {
+				dValue = dValue.Elem()
+			}
+
+			if dValue.Kind() == reflect.Map || dValue.Kind() == reflect.Slice {
+				// we only compare primitive types
+				continue
+			}
+			x := src.MapIndex(dKey)
+			if x.Kind() == reflect.Interface {
+				x = x.Elem()
+			}
+			// check if element exists in src map and value is the same as in dst map
+			if x.IsValid() && dValue.IsValid() && dValue.Interface() == x.Interface() {
+				comparison = true
+				continue
+			}
+			// if value does not exist in src map -> continue
+			if !x.IsValid() && dValue.IsValid() {
+				uniqueDest = true
+				continue
+			}
+			comparison = true
+			match = false
+		}
+		// Check if all primitive values have matched AND at least one comparison was done
+		if match && comparison && !(uniqueSource && uniqueDest) {
+			return i
+		}
+	}
+	return -1
+}
+
+func hasMergeKey(src reflect.Value, opts MergeOptions) bool {
+	return opts.MergeKey != "" && src.Kind() == reflect.Map && mapHasKey(src, opts.MergeKey)
+}
+
+// patchDirective returns the value of the `$patch` key of a map.
+func patchDirective(v reflect.Value) string {
+	if v.Kind() == reflect.Interface {
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Map {
+		return ""
+	}
+	directive, _ := mapIndex(v, "$patch").(string)
+	return directive
+}
+
+func isDirectiveKey(key reflect.Value) bool {
+	if key.Kind() != reflect.String {
+		return false
+	}
+	return key.String() == "$patch" || key.String() == "$retainKeys" || strings.HasPrefix(key.String(), "$setElementOrder/")
+}
+
+// listDirective returns the directive of a list item which only consists of
+// a `$patch` key, e.g. `- $patch: replace`. Items with other keys carry
+// item-level directives and are not list directives.
+func listDirective(v reflect.Value) string {
+	if v.Kind() == reflect.Interface {
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Map || v.Len() != 1 {
+		return ""
+	}
+	return patchDirective(v)
+}
+
+// hasListReplaceDirective returns true if a list contains a `- $patch: replace` item.
+func hasListReplaceDirective(list reflect.Value) bool {
+	for i := 0; i < list.Len(); i++ {
+		if listDirective(list.Index(i)) == "replace" {
+			return true
+		}
+	}
+	return false
+}
+
+// stripDirectives returns a copy of v without any strategic merge patch
+// directives.
+func stripDirectives(v interface{}) interface{} {
+	value := reflect.ValueOf(v)
+	switch value.Kind() {
+	case reflect.Map:
+		c := reflect.MakeMapWithSize(value.Type(), value.Len())
+		iter := value.MapRange()
+		for iter.Next() {
+			key := iter.Key()
+			if key.Kind() == reflect.Interface {
+				key = key.Elem()
+			}
+			if isDirectiveKey(key) {
+				continue
+			}
+			c.SetMapIndex(iter.Key(), reflectValue(stripDirectives(iter.Value().Interface()), value.Type().Elem()))
+		}
+		return c.Interface()
+	case reflect.Slice:
+		c := reflect.MakeSlice(value.Type(), 0, value.Len())
+		for i := 0; i < value.Len(); i++ {
+			item := value.Index(i)
+			if item.Kind() == reflect.Interface {
+				item = item.Elem()
+			}
+			// drop list directives like `- $patch: replace`
+			if listDirective(item) != "" {
+				continue
+			}
+			c = reflect.Append(c, reflectValue(stripDirectives(value.Index(i).Interface()), value.Type().Elem()))
+		}
+		return c.Interface()
+	}
+	return v
+}
+
+// applyRetainKeys removes all keys from dst which are not listed in the
+// `$retainKeys` directive of src.
+func applyRetainKeys(dst, src reflect.Value) {
+	retainKeys := reflect.ValueOf(mapIndex(src, "$retainKeys"))
+	if retainKeys.Kind() != reflect.Slice {
+		return
+	}
+	retain := map[string]bool{}
+	for i := 0; i < retainKeys.Len(); i++ {
+		retain[fmt.Sprint(retainKeys.Index(i).Interface())] = true
+	}
+	for _, key := range dst.MapKeys() {
+		if !retain[fmt.Sprint(key.Interface())] {
+			dst.SetMapIndex(key, reflect.Value{})
+		}
+	}
+}
 
-				if dValue.Kind() == reflect.Map || dValue.Kind() == reflect.Slice {
-					// we only compare primitive types
+// applySetElementOrder reorders lists in dst according to the
+// `$setElementOrder/<list>` directives of src. Items not listed in the
+// directive are kept after the listed items in their current order.
+func applySetElementOrder(dst, src reflect.Value, opts MergeOptions) {
+	iter := src.MapRange()
+	for iter.Next() {
+		key := fmt.Sprint(iter.Key().Interface())
+		if !strings.HasPrefix(key, "$setElementOrder/") {
+			continue
+		}
+		order := reflect.ValueOf(iter.Value().Interface())
+		listKey := mapKey(dst, strings.TrimPrefix(key, "$setElementOrder/"))
+		list := dst.MapIndex(listKey)
+		if list.IsValid() {
+			list = reflect.ValueOf(list.Interface())
+		}
+		if order.Kind() != reflect.Slice || list.Kind() != reflect.Slice {
+			continue
+		}
+		used := make([]bool, list.Len())
+		result := reflect.MakeSlice(list.Type(), 0, list.Len())
+		for i := 0; i < order.Len(); i++ {
+			o := order.Index(i)
+			if o.Kind() == reflect.Interface {
+				o = o.Elem()
+			}
+			for j := 0; j < list.Len(); j++ {
+				if used[j] {
 					continue
 				}
-				x := src.MapIndex(dKey)
-				if x.Kind() == reflect.Interface {
-					x = x.Elem()
+				item := list.Index(j)
+				if item.Kind() == reflect.Interface {
+					item = item.Elem()
 				}
-				// check if element exists in src map and value is the same as in dst map
-				if x.IsValid() && dValue.IsValid() && dValue.Interface() == x.Interface() {
-					comparison = true
-					continue
+				var matched bool
+				if o.Kind() == reflect.Map && item.Kind() == reflect.Map {
+					matched = matchListItem(reflect.ValueOf([]interface{}{item.Interface()}), o, MergeOptions{MergeKey: opts.MergeKey}) == 0
+				} else {
+					matched = jsonEqual(o.Interface(), item.Interface())
 				}
-				// if value does not exist in src map -> continue
-				if !x.IsValid() && dValue.IsValid() {
-					uniqueDest = true
-					continue
+				if matched {
+					used[j] = true
+					result = reflect.Append(result, list.Index(j))
+					break
 				}
-				comparison = true
-				match = false
 			}
-			// Check if all primitive values have matched AND at least one comparison was done
-			if match && comparison && !(uniqueSource && uniqueDest) {
-				dv := reflect.ValueOf(dst.MapIndex(key).Elem().Index(i).Interface())
-				MergeMaps(dv, src, mergeListItems)
-				return
+		}
+		for j := 0; j < list.Len(); j++ {
+			if !used[j] {
+				result = reflect.Append(result, list.Index(j))
 			}
 		}
+		dst.SetMapIndex(listKey, result)
+	}
+}
 
-	} else {
-		// check if primitive value exists in dst and if so return
-		slice := dst.MapIndex(key).Elem()
-		for i := 0; i < slice.Len(); i++ {
-			element := slice.Index(i).Elem()
-			if element.IsValid() && src.IsValid() && element.Kind() != reflect.Map && element.Kind() != reflect.Slice && element.Interface() == src.Interface() {
-				return
+// ApplyDefaults adds values from defaults to dst where a key is missing or
+// null. Maps in defaults are applied to maps in dst and to every map element
+// of lists in dst, or added to dst when missing.
+func ApplyDefaults(dst, defaults reflect.Value) error {
+	if dst.Kind() == reflect.Interface {
+		dst = dst.Elem()
+	}
+	if defaults.Kind() == reflect.Interface {
+		defaults = defaults.Elem()
+	}
+	if dst.Kind() == reflect.Slice {
+		for i := 0; i < dst.Len(); i++ {
+			element := dst.Index(i)
+			if element.Kind() == reflect.Interface {
+				element = element.Elem()
+			}
+			if element.Kind() == reflect.Map {
+				err := ApplyDefaults(element, defaults)
+				if err != nil {
+					return err
+				}
 			}
 		}
+		return nil
 	}
-	dst.SetMapIndex(key, reflect.Append(dValue, src))
+	if dst.Kind() != reflect.Map || defaults.Kind() != reflect.Map {
+		return fmt.Errorf("[ERROR] dst and/or defaults in ApplyDefaults not a Map.")
+	}
+	iter := defaults.MapRange()
+	for iter.Next() {
+		key := iter.Key()
+		if key.Kind() == reflect.Interface {
+			key = key.Elem()
+		}
+		if !key.Type().AssignableTo(dst.Type().Key()) {
+			continue
+		}
+		value := iter.Value()
+		if value.Kind() == reflect.Interface {
+			value = value.Elem()
+		}
+		if !value.IsValid() {
+			continue
+		}
+
+		dValue := dst.MapIndex(key)
+		if dValue.Kind() == reflect.Interface {
+			dValue = dValue.Elem()
+		}
+		if value.Kind() == reflect.Map && (dValue.Kind() == reflect.Map || dValue.Kind() == reflect.Slice) {
+			err := ApplyDefaults(dValue, value)
+			if err != nil {
+				return err
+			}
+		} else if !dValue.IsValid() {
+			dst.SetMapIndex(key, reflect.ValueOf(deepCopy(value.Interface())))
+		}
+	}
+	return nil
+}
+
+// deepCopy returns a copy of a decoded YAML value, where maps and slices are
+// not shared with the original.
+func deepCopy(v interface{}) interface{} {
+	value := reflect.ValueOf(v)
+	switch value.Kind() {
+	case reflect.Map:
+		c := reflect.MakeMapWithSize(value.Type(), value.Len())
+		iter := value.MapRange()
+		for iter.Next() {
+			c.SetMapIndex(iter.Key(), reflectValue(deepCopy(iter.Value().Interface()), value.Type().Elem()))
+		}
+		return c.Interface()
+	case reflect.Slice:
+		c := reflect.MakeSlice(value.Type(), value.Len(), value.Len())
+		for i := 0; i < value.Len(); i++ {
+			c.Index(i).Set(reflectValue(deepCopy(value.Index(i).Interface()), value.Type().Elem()))
+		}
+		return c.Interface()
+	}
+	return v
+}
+
+// reflectValue returns the reflect.Value of v, or the zero value of t if v is nil.
+func reflectValue(v interface{}, t reflect.Type) reflect.Value {
+	if v == nil {
+		return reflect.Zero(t)
+	}
+	return reflect.ValueOf(v)
 }
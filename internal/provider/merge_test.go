@@ -68,11 +68,22 @@ func TestMergeMaps(t *testing.T) {
 	}
 
 	for _, c := range cases {
-		MergeMaps(reflect.ValueOf(c.dst), reflect.ValueOf(c.src), true)
+		err := MergeMaps(reflect.ValueOf(c.dst), reflect.ValueOf(c.src), true)
+		if err != nil {
+			t.Fatalf("Error merging maps: %s", err)
+		}
 		if !reflect.DeepEqual(c.dst, c.result) {
 			t.Fatalf("Error matching dst and result: %#v vs %#v", c.dst, c.result)
 		}
 	}
+
+	// errors of nested maps are returned
+	dst := map[interface{}]interface{}{"root": map[interface{}]interface{}{"child1": "abc"}}
+	src := map[interface{}]interface{}{"root": map[interface{}]interface{}{"child1": map[interface{}]interface{}{"child2": "def"}}}
+	err := MergeMaps(reflect.ValueOf(dst), reflect.ValueOf(src), true)
+	if err == nil {
+		t.Fatalf("Expected error merging a map into a string")
+	}
 }
 
 func TestListItem(t *testing.T) {
@@ -315,7 +326,358 @@ func TestListItem(t *testing.T) {
 	}
 
 	for _, c := range cases {
-		MergeListItem(reflect.ValueOf(c.dst), reflect.ValueOf(c.key), reflect.ValueOf(c.src), c.mergeListItems)
+		err := MergeListItem(reflect.ValueOf(c.dst), reflect.ValueOf(c.key), reflect.ValueOf(c.src), c.mergeListItems)
+		if err != nil {
+			t.Fatalf("Error merging list item: %s", err)
+		}
+		if !reflect.DeepEqual(c.dst, c.result) {
+			t.Fatalf("Error matching dst and result: %#v vs %#v", c.dst, c.result)
+		}
+	}
+}
+
+func TestApplyDefaults(t *testing.T) {
+	cases := []struct {
+		dst      map[interface{}]interface{}
+		defaults map[interface{}]interface{}
+		result   map[interface{}]interface{}
+	}{
+		// add missing values
+		{
+			dst: map[interface{}]interface{}{
+				"e1": "abc",
+			},
+			defaults: map[interface{}]interface{}{
+				"e1": "def",
+				"e2": "ghi",
+			},
+			result: map[interface{}]interface{}{
+				"e1": "abc",
+				"e2": "ghi",
+			},
+		},
+		// replace null values
+		{
+			dst: map[interface{}]interface{}{
+				"e1": nil,
+			},
+			defaults: map[interface{}]interface{}{
+				"e1": "abc",
+			},
+			result: map[interface{}]interface{}{
+				"e1": "abc",
+			},
+		},
+		// apply defaults to every list item
+		{
+			dst: map[interface{}]interface{}{
+				"tenants": []interface{}{
+					map[string]interface{}{
+						"name": "t1",
+						"vrfs": []interface{}{
+							map[string]interface{}{
+								"name":     "v1",
+								"enforced": false,
+							},
+							map[string]interface{}{
+								"name": "v2",
+							},
+						},
+					},
+				},
+			},
+			defaults: map[interface{}]interface{}{
+				"tenants": map[string]interface{}{
+					"vrfs": map[string]interface{}{
+						"enforced": true,
+						"tags":     []interface{}{"a"},
+					},
+				},
+			},
+			result: map[interface{}]interface{}{
+				"tenants": []interface{}{
+					map[string]interface{}{
+						"name": "t1",
+						"vrfs": []interface{}{
+							map[string]interface{}{
+								"name":     "v1",
+								"enforced": false,
+								"tags":     []interface{}{"a"},
+							},
+							map[string]interface{}{
+								"name":     "v2",
+								"enforced": true,
+								"tags":     []interface{}{"a"},
+							},
+						},
+					},
+				},
+			},
+		},
+		// add missing maps
+		{
+			dst: map[interface{}]interface{}{
+				"e1": "abc",
+			},
+			defaults: map[interface{}]interface{}{
+				"root": map[string]interface{}{
+					"child1": "def",
+				},
+			},
+			result: map[interface{}]interface{}{
+				"e1": "abc",
+				"root": map[string]interface{}{
+					"child1": "def",
+				},
+			},
+		},
+		// add missing nested maps to list items
+		{
+			dst: map[interface{}]interface{}{
+				"tenants": []interface{}{
+					map[string]interface{}{
+						"name": "t1",
+					},
+					map[string]interface{}{
+						"name": "t2",
+						"bgp": map[string]interface{}{
+							"asn": 2,
+						},
+					},
+				},
+			},
+			defaults: map[interface{}]interface{}{
+				"tenants": map[string]interface{}{
+					"bgp": map[string]interface{}{
+						"asn":   1,
+						"peers": []interface{}{"p1"},
+					},
+				},
+			},
+			result: map[interface{}]interface{}{
+				"tenants": []interface{}{
+					map[string]interface{}{
+						"name": "t1",
+						"bgp": map[string]interface{}{
+							"asn":   1,
+							"peers": []interface{}{"p1"},
+						},
+					},
+					map[string]interface{}{
+						"name": "t2",
+						"bgp": map[string]interface{}{
+							"asn":   2,
+							"peers": []interface{}{"p1"},
+						},
+					},
+				},
+			},
+		},
+		// keep scalar values where defaults have a map
+		{
+			dst: map[interface{}]interface{}{
+				"root": "abc",
+			},
+			defaults: map[interface{}]interface{}{
+				"root": map[string]interface{}{
+					"child1": "def",
+				},
+			},
+			result: map[interface{}]interface{}{
+				"root": "abc",
+			},
+		},
+	}
+
+	for _, c := range cases {
+		err := ApplyDefaults(reflect.ValueOf(c.dst), reflect.ValueOf(c.defaults))
+		if err != nil {
+			t.Fatalf("Error applying defaults: %s", err)
+		}
+		if !reflect.DeepEqual(c.dst, c.result) {
+			t.Fatalf("Error matching dst and result: %#v vs %#v", c.dst, c.result)
+		}
+	}
+}
+
+func TestMergeMapsStrategic(t *testing.T) {
+	cases := []struct {
+		dst      map[interface{}]interface{}
+		src      map[interface{}]interface{}
+		mergeKey string
+		result   map[interface{}]interface{}
+	}{
+		// replace map
+		{
+			dst: map[interface{}]interface{}{
+				"root": map[interface{}]interface{}{
+					"child1": "abc",
+				},
+			},
+			src: map[interface{}]interface{}{
+				"root": map[interface{}]interface{}{
+					"$patch": "replace",
+					"child2": "def",
+				},
+			},
+			result: map[interface{}]interface{}{
+				"root": map[interface{}]interface{}{
+					"child2": "def",
+				},
+			},
+		},
+		// delete map
+		{
+			dst: map[interface{}]interface{}{
+				"root": map[interface{}]interface{}{
+					"child1": "abc",
+				},
+				"e1": "abc",
+			},
+			src: map[interface{}]interface{}{
+				"root": map[interface{}]interface{}{
+					"$patch": "delete",
+				},
+			},
+			result: map[interface{}]interface{}{
+				"e1": "abc",
+			},
+		},
+		// replace list
+		{
+			dst: map[interface{}]interface{}{
+				"list": []interface{}{"a1", "a2"},
+			},
+			src: map[interface{}]interface{}{
+				"list": []interface{}{
+					map[string]interface{}{"$patch": "replace"},
+					"a3",
+				},
+			},
+			result: map[interface{}]interface{}{
+				"list": []interface{}{"a3"},
+			},
+		},
+		// delete list item
+		{
+			dst: map[interface{}]interface{}{
+				"list": []interface{}{
+					map[string]interface{}{"name": "a1", "value": 1},
+					map[string]interface{}{"name": "a2"},
+				},
+			},
+			src: map[interface{}]interface{}{
+				"list": []interface{}{
+					map[string]interface{}{"name": "a1", "$patch": "delete"},
+					map[string]interface{}{"name": "a3", "$patch": "delete"},
+				},
+			},
+			mergeKey: "name",
+			result: map[interface{}]interface{}{
+				"list": []interface{}{
+					map[string]interface{}{"name": "a2"},
+				},
+			},
+		},
+		// replace a single list item, other items are kept
+		{
+			dst: map[interface{}]interface{}{
+				"list": []interface{}{
+					map[string]interface{}{"name": "a", "x": 1, "y": 2},
+					map[string]interface{}{"name": "b"},
+				},
+			},
+			src: map[interface{}]interface{}{
+				"list": []interface{}{
+					map[string]interface{}{"name": "a", "$patch": "replace", "x": 3},
+				},
+			},
+			mergeKey: "name",
+			result: map[interface{}]interface{}{
+				"list": []interface{}{
+					map[string]interface{}{"name": "a", "x": 3},
+					map[string]interface{}{"name": "b"},
+				},
+			},
+		},
+		// merge list items by merge key
+		{
+			dst: map[interface{}]interface{}{
+				"list": []interface{}{
+					map[string]interface{}{"name": "a1", "value": 1},
+				},
+			},
+			src: map[interface{}]interface{}{
+				"list": []interface{}{
+					map[string]interface{}{"name": "a1", "value": 2},
+					map[string]interface{}{"name": "a2", "child": map[string]interface{}{"$patch": "merge", "c": 1}},
+				},
+			},
+			mergeKey: "name",
+			result: map[interface{}]interface{}{
+				"list": []interface{}{
+					map[string]interface{}{"name": "a1", "value": 2},
+					map[string]interface{}{"name": "a2", "child": map[string]interface{}{"c": 1}},
+				},
+			},
+		},
+		// retain keys
+		{
+			dst: map[interface{}]interface{}{
+				"root": map[interface{}]interface{}{
+					"child1": "abc",
+					"child2": "def",
+				},
+			},
+			src: map[interface{}]interface{}{
+				"root": map[interface{}]interface{}{
+					"$retainKeys": []interface{}{"child2", "child3"},
+					"child3":      "ghi",
+				},
+			},
+			result: map[interface{}]interface{}{
+				"root": map[interface{}]interface{}{
+					"child2": "def",
+					"child3": "ghi",
+				},
+			},
+		},
+		// set element order
+		{
+			dst: map[interface{}]interface{}{
+				"list": []interface{}{
+					map[string]interface{}{"name": "a1"},
+					map[string]interface{}{"name": "a2"},
+				},
+				"primitives": []interface{}{"a1", "a2"},
+			},
+			src: map[interface{}]interface{}{
+				"$setElementOrder/list": []interface{}{
+					map[string]interface{}{"name": "a3"},
+					map[string]interface{}{"name": "a2"},
+				},
+				"$setElementOrder/primitives": []interface{}{"a2"},
+				"list": []interface{}{
+					map[string]interface{}{"name": "a3"},
+				},
+			},
+			mergeKey: "name",
+			result: map[interface{}]interface{}{
+				"list": []interface{}{
+					map[string]interface{}{"name": "a3"},
+					map[string]interface{}{"name": "a2"},
+					map[string]interface{}{"name": "a1"},
+				},
+				"primitives": []interface{}{"a2", "a1"},
+			},
+		},
+	}
+
+	for _, c := range cases {
+		err := MergeMapsWithOptions(reflect.ValueOf(c.dst), reflect.ValueOf(c.src), MergeOptions{MergeListItems: true, Strategic: true, MergeKey: c.mergeKey})
+		if err != nil {
+			t.Fatalf("Error merging maps: %s", err)
+		}
 		if !reflect.DeepEqual(c.dst, c.result) {
 			t.Fatalf("Error matching dst and result: %#v vs %#v", c.dst, c.result)
 		}
@@ -0,0 +1,299 @@
+package provider
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// ApplyJsonPatch applies a JSON Patch (RFC 6902) to a decoded YAML document
+// and returns the patched document. The patch is applied atomically, the
+// original document is never modified.
+func ApplyJsonPatch(doc interface{}, patch []interface{}) (interface{}, error) {
+	doc = deepCopy(doc)
+	for i, o := range patch {
+		operation := reflect.ValueOf(o)
+		if operation.Kind() != reflect.Map {
+			return nil, fmt.Errorf("operation %d: not a map", i)
+		}
+		op, _ := mapIndex(operation, "op").(string)
+		path, ok := mapIndex(operation, "path").(string)
+		if !ok {
+			return nil, fmt.Errorf("operation %d: missing 'path'", i)
+		}
+		tokens, err := parseJsonPointer(path)
+		if err != nil {
+			return nil, fmt.Errorf("operation %d: %s", i, err)
+		}
+		value := mapIndex(operation, "value")
+		if (op == "add" || op == "replace" || op == "test") && !mapHasKey(operation, "value") {
+			return nil, fmt.Errorf("operation %d: missing 'value'", i)
+		}
+		var fromTokens []string
+		if op == "move" || op == "copy" {
+			from, ok := mapIndex(operation, "from").(string)
+			if !ok {
+				return nil, fmt.Errorf("operation %d: missing 'from'", i)
+			}
+			fromTokens, err = parseJsonPointer(from)
+			if err != nil {
+				return nil, fmt.Errorf("operation %d: %s", i, err)
+			}
+		}
+
+		switch op {
+		case "add":
+			doc, err = jsonPatchAdd(doc, tokens, deepCopy(value))
+		case "remove":
+			doc, _, err = jsonPatchRemove(doc, tokens)
+		case "replace":
+			doc, _, err = jsonPatchRemove(doc, tokens)
+			if err == nil {
+				doc, err = jsonPatchAdd(doc, tokens, deepCopy(value))
+			}
+		case "move":
+			if len(fromTokens) < len(tokens) && reflect.DeepEqual(fromTokens, tokens[:len(fromTokens)]) {
+				return nil, fmt.Errorf("operation %d: cannot move '%s' into one of its children", i, mapIndex(operation, "from"))
+			}
+			var moved interface{}
+			doc, moved, err = jsonPatchRemove(doc, fromTokens)
+			if err == nil {
+				doc, err = jsonPatchAdd(doc, tokens, moved)
+			}
+		case "copy":
+			var copied interface{}
+			copied, err = jsonPatchGet(doc, fromTokens)
+			if err == nil {
+				doc, err = jsonPatchAdd(doc, tokens, deepCopy(copied))
+			}
+		case "test":
+			var actual interface{}
+			actual, err = jsonPatchGet(doc, tokens)
+			if err == nil && !jsonEqual(actual, value) {
+				err = fmt.Errorf("test failed, value at '%s' is not equal to %v", path, value)
+			}
+		default:
+			return nil, fmt.Errorf("operation %d: unknown op '%s'", i, op)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("operation %d: %s", i, err)
+		}
+	}
+	return doc, nil
+}
+
+// ApplyJsonMergePatch applies a JSON Merge Patch (RFC 7386) to a decoded YAML
+// document and returns the patched document.
+func ApplyJsonMergePatch(doc, patch interface{}) interface{} {
+	p := reflect.ValueOf(patch)
+	if p.Kind() != reflect.Map {
+		return deepCopy(patch)
+	}
+	target := reflect.ValueOf(deepCopy(doc))
+	if target.Kind() != reflect.Map {
+		target = reflect.ValueOf(map[string]interface{}{})
+	}
+	iter := p.MapRange()
+	for iter.Next() {
+		key := mapKey(target, iter.Key().Interface())
+		value := iter.Value().Interface()
+		if value == nil {
+			target.SetMapIndex(key, reflect.Value{})
+			continue
+		}
+		target.SetMapIndex(key, reflectValue(ApplyJsonMergePatch(mapIndex(target, key.Interface()), value), target.Type().Elem()))
+	}
+	return target.Interface()
+}
+
+// parseJsonPointer splits a JSON Pointer (RFC 6901) into its unescaped
+// reference tokens.
+func parseJsonPointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return []string{}, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("invalid JSON pointer '%s'", pointer)
+	}
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+	}
+	return tokens, nil
+}
+
+func jsonPatchIndex(token string, length int, allowEnd bool) (int, error) {
+	if token == "-" && allowEnd {
+		return length, nil
+	}
+	index, err := strconv.Atoi(token)
+	if err != nil || index < 0 || token != strconv.Itoa(index) {
+		return 0, fmt.Errorf("invalid array index '%s'", token)
+	}
+	if index > length || index == length && !allowEnd {
+		return 0, fmt.Errorf("array index %d out of bounds", index)
+	}
+	return index, nil
+}
+
+func jsonPatchGet(doc interface{}, tokens []string) (interface{}, error) {
+	for _, token := range tokens {
+		value := reflect.ValueOf(doc)
+		switch value.Kind() {
+		case reflect.Map:
+			key := mapKey(value, token)
+			if !value.MapIndex(key).IsValid() {
+				return nil, fmt.Errorf("path '%s' does not exist", token)
+			}
+			doc = value.MapIndex(key).Interface()
+		case reflect.Slice:
+			index, err := jsonPatchIndex(token, value.Len(), false)
+			if err != nil {
+				return nil, err
+			}
+			doc = value.Index(index).Interface()
+		default:
+			return nil, fmt.Errorf("path '%s' does not exist", token)
+		}
+	}
+	return doc, nil
+}
+
+// jsonPatchUpdate navigates to the parent of the location referenced by
+// tokens and replaces it with the result of fn. Slices are rebuilt on the way
+// back up, as they cannot be modified in place.
+func jsonPatchUpdate(doc interface{}, tokens []string, fn func(parent interface{}, token string) (interface{}, error)) (interface{}, error) {
+	if len(tokens) == 1 {
+		return fn(doc, tokens[0])
+	}
+	child, err := jsonPatchGet(doc, tokens[:1])
+	if err != nil {
+		return nil, err
+	}
+	child, err = jsonPatchUpdate(child, tokens[1:], fn)
+	if err != nil {
+		return nil, err
+	}
+	value := reflect.ValueOf(doc)
+	if value.Kind() == reflect.Map {
+		value.SetMapIndex(mapKey(value, tokens[0]), reflectValue(child, value.Type().Elem()))
+	} else {
+		index, _ := jsonPatchIndex(tokens[0], value.Len(), false)
+		value.Index(index).Set(reflectValue(child, value.Type().Elem()))
+	}
+	return doc, nil
+}
+
+func jsonPatchAdd(doc interface{}, tokens []string, v interface{}) (interface{}, error) {
+	if len(tokens) == 0 {
+		return v, nil
+	}
+	return jsonPatchUpdate(doc, tokens, func(parent interface{}, token string) (interface{}, error) {
+		value := reflect.ValueOf(parent)
+		switch value.Kind() {
+		case reflect.Map:
+			value.SetMapIndex(mapKey(value, token), reflectValue(v, value.Type().Elem()))
+			return parent, nil
+		case reflect.Slice:
+			index, err := jsonPatchIndex(token, value.Len(), true)
+			if err != nil {
+				return nil, err
+			}
+			result := reflect.MakeSlice(value.Type(), 0, value.Len()+1)
+			result = reflect.AppendSlice(result, value.Slice(0, index))
+			result = reflect.Append(result, reflectValue(v, value.Type().Elem()))
+			result = reflect.AppendSlice(result, value.Slice(index, value.Len()))
+			return result.Interface(), nil
+		}
+		return nil, fmt.Errorf("path '%s' does not exist", token)
+	})
+}
+
+func jsonPatchRemove(doc interface{}, tokens []string) (interface{}, interface{}, error) {
+	if len(tokens) == 0 {
+		return nil, doc, nil
+	}
+	var removed interface{}
+	doc, err := jsonPatchUpdate(doc, tokens, func(parent interface{}, token string) (interface{}, error) {
+		value := reflect.ValueOf(parent)
+		switch value.Kind() {
+		case reflect.Map:
+			key := mapKey(value, token)
+			if !value.MapIndex(key).IsValid() {
+				return nil, fmt.Errorf("path '%s' does not exist", token)
+			}
+			removed = value.MapIndex(key).Interface()
+			value.SetMapIndex(key, reflect.Value{})
+			return parent, nil
+		case reflect.Slice:
+			index, err := jsonPatchIndex(token, value.Len(), false)
+			if err != nil {
+				return nil, err
+			}
+			removed = value.Index(index).Interface()
+			result := reflect.MakeSlice(value.Type(), 0, value.Len()-1)
+			result = reflect.AppendSlice(result, value.Slice(0, index))
+			result = reflect.AppendSlice(result, value.Slice(index+1, value.Len()))
+			return result.Interface(), nil
+		}
+		return nil, fmt.Errorf("path '%s' does not exist", token)
+	})
+	return doc, removed, err
+}
+
+// mapKey returns a key usable with the map m. Existing keys which are not
+// strings, e.g. integer keys, are matched by their string representation.
+func mapKey(m reflect.Value, key interface{}) reflect.Value {
+	k := reflect.ValueOf(key)
+	if !k.IsValid() {
+		k = reflect.ValueOf(fmt.Sprint(key))
+	}
+	if k.Type().AssignableTo(m.Type().Key()) && m.MapIndex(k).IsValid() {
+		return k
+	}
+	for _, existing := range m.MapKeys() {
+		if fmt.Sprint(existing.Interface()) == fmt.Sprint(key) {
+			return existing
+		}
+	}
+	if !k.Type().AssignableTo(m.Type().Key()) {
+		return reflect.ValueOf(fmt.Sprint(key))
+	}
+	return k
+}
+
+// jsonEqual compares two decoded documents using JSON semantics, where
+// numbers are compared by value regardless of their type.
+func jsonEqual(a, b interface{}) bool {
+	if fa, ok := queryNumber(a); ok {
+		fb, ok := queryNumber(b)
+		return ok && fa == fb
+	}
+	va, vb := reflect.ValueOf(a), reflect.ValueOf(b)
+	if va.Kind() == reflect.Map && vb.Kind() == reflect.Map {
+		if va.Len() != vb.Len() {
+			return false
+		}
+		iter := va.MapRange()
+		for iter.Next() {
+			key := mapKey(vb, iter.Key().Interface())
+			if !vb.MapIndex(key).IsValid() || !jsonEqual(iter.Value().Interface(), vb.MapIndex(key).Interface()) {
+				return false
+			}
+		}
+		return true
+	}
+	if va.Kind() == reflect.Slice && vb.Kind() == reflect.Slice {
+		if va.Len() != vb.Len() {
+			return false
+		}
+		for i := 0; i < va.Len(); i++ {
+			if !jsonEqual(va.Index(i).Interface(), vb.Index(i).Interface()) {
+				return false
+			}
+		}
+		return true
+	}
+	return reflect.DeepEqual(a, b)
+}
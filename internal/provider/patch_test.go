@@ -0,0 +1,205 @@
+package provider
+
+import (
+	"testing"
+)
+
+// Examples from RFC 6902, Appendix A
+func TestApplyJsonPatch(t *testing.T) {
+	cases := []struct {
+		doc    string
+		patch  string
+		result string
+		err    bool
+	}{
+		// A.1. Adding an Object Member
+		{
+			doc:    `{"foo": "bar"}`,
+			patch:  `[{"op": "add", "path": "/baz", "value": "qux"}]`,
+			result: `{"baz": "qux", "foo": "bar"}`,
+		},
+		// A.2. Adding an Array Element
+		{
+			doc:    `{"foo": ["bar", "baz"]}`,
+			patch:  `[{"op": "add", "path": "/foo/1", "value": "qux"}]`,
+			result: `{"foo": ["bar", "qux", "baz"]}`,
+		},
+		// A.3. Removing an Object Member
+		{
+			doc:    `{"baz": "qux", "foo": "bar"}`,
+			patch:  `[{"op": "remove", "path": "/baz"}]`,
+			result: `{"foo": "bar"}`,
+		},
+		// A.4. Removing an Array Element
+		{
+			doc:    `{"foo": ["bar", "qux", "baz"]}`,
+			patch:  `[{"op": "remove", "path": "/foo/1"}]`,
+			result: `{"foo": ["bar", "baz"]}`,
+		},
+		// A.5. Replacing a Value
+		{
+			doc:    `{"baz": "qux", "foo": "bar"}`,
+			patch:  `[{"op": "replace", "path": "/baz", "value": "boo"}]`,
+			result: `{"baz": "boo", "foo": "bar"}`,
+		},
+		// A.6. Moving a Value
+		{
+			doc:    `{"foo": {"bar": "baz", "waldo": "fred"}, "qux": {"corge": "grault"}}`,
+			patch:  `[{"op": "move", "from": "/foo/waldo", "path": "/qux/thud"}]`,
+			result: `{"foo": {"bar": "baz"}, "qux": {"corge": "grault", "thud": "fred"}}`,
+		},
+		// A.7. Moving an Array Element
+		{
+			doc:    `{"foo": ["all", "grass", "cows", "eat"]}`,
+			patch:  `[{"op": "move", "from": "/foo/1", "path": "/foo/3"}]`,
+			result: `{"foo": ["all", "cows", "eat", "grass"]}`,
+		},
+		// A.8. Testing a Value: Success
+		{
+			doc:    `{"baz": "qux", "foo": ["a", 2, "c"]}`,
+			patch:  `[{"op": "test", "path": "/baz", "value": "qux"}, {"op": "test", "path": "/foo/1", "value": 2}]`,
+			result: `{"baz": "qux", "foo": ["a", 2, "c"]}`,
+		},
+		// A.9. Testing a Value: Error
+		{
+			doc:   `{"baz": "qux"}`,
+			patch: `[{"op": "test", "path": "/baz", "value": "bar"}]`,
+			err:   true,
+		},
+		// A.10. Adding a Nested Member Object
+		{
+			doc:    `{"foo": "bar"}`,
+			patch:  `[{"op": "add", "path": "/child", "value": {"grandchild": {}}}]`,
+			result: `{"foo": "bar", "child": {"grandchild": {}}}`,
+		},
+		// A.11. Ignoring Unrecognized Elements
+		{
+			doc:    `{"foo": "bar"}`,
+			patch:  `[{"op": "add", "path": "/baz", "value": "qux", "xyz": 123}]`,
+			result: `{"foo": "bar", "baz": "qux"}`,
+		},
+		// A.12. Adding to a Nonexistent Target
+		{
+			doc:   `{"foo": "bar"}`,
+			patch: `[{"op": "add", "path": "/baz/bat", "value": "qux"}]`,
+			err:   true,
+		},
+		// A.14. ~ Escape Ordering
+		{
+			doc:    `{"/": 9, "~1": 10}`,
+			patch:  `[{"op": "test", "path": "/~01", "value": 10}]`,
+			result: `{"/": 9, "~1": 10}`,
+		},
+		// A.15. Comparing Strings and Numbers
+		{
+			doc:   `{"/": 9, "~1": 10}`,
+			patch: `[{"op": "test", "path": "/~01", "value": "10"}]`,
+			err:   true,
+		},
+		// A.16. Adding an Array Value
+		{
+			doc:    `{"foo": ["bar"]}`,
+			patch:  `[{"op": "add", "path": "/foo/-", "value": ["abc", "def"]}]`,
+			result: `{"foo": ["bar", ["abc", "def"]]}`,
+		},
+		// replacing the whole document
+		{
+			doc:    `{"foo": "bar"}`,
+			patch:  `[{"op": "replace", "path": "", "value": ["abc"]}]`,
+			result: `["abc"]`,
+		},
+		// copying a value
+		{
+			doc:    `{"foo": {"bar": [1]}}`,
+			patch:  `[{"op": "copy", "from": "/foo/bar", "path": "/baz"}, {"op": "add", "path": "/baz/-", "value": 2}]`,
+			result: `{"foo": {"bar": [1]}, "baz": [1, 2]}`,
+		},
+		// moving a value into one of its children
+		{
+			doc:   `{"foo": {"bar": 1}}`,
+			patch: `[{"op": "move", "from": "/foo", "path": "/foo/bar/baz"}]`,
+			err:   true,
+		},
+		// array index out of bounds
+		{
+			doc:   `{"foo": ["bar"]}`,
+			patch: `[{"op": "add", "path": "/foo/2", "value": "baz"}]`,
+			err:   true,
+		},
+		// leading zeros in array index
+		{
+			doc:   `{"foo": ["bar", "baz"]}`,
+			patch: `[{"op": "remove", "path": "/foo/01"}]`,
+			err:   true,
+		},
+	}
+
+	for _, c := range cases {
+		var doc, result interface{}
+		var patch []interface{}
+		if err := YamlUnmarshal([]byte(c.doc), &doc); err != nil {
+			t.Fatalf("Error reading document: %s", err)
+		}
+		if err := YamlUnmarshal([]byte(c.patch), &patch); err != nil {
+			t.Fatalf("Error reading patch: %s", err)
+		}
+		patched, err := ApplyJsonPatch(doc, patch)
+		if c.err {
+			if err == nil {
+				t.Fatalf("Expected error applying patch %s", c.patch)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("Error applying patch %s: %s", c.patch, err)
+		}
+		if err := YamlUnmarshal([]byte(c.result), &result); err != nil {
+			t.Fatalf("Error reading result: %s", err)
+		}
+		if !jsonEqual(patched, result) {
+			t.Fatalf("Error matching patched document and result: %#v vs %#v", patched, result)
+		}
+	}
+}
+
+// Examples from RFC 7386, Appendix A
+func TestApplyJsonMergePatch(t *testing.T) {
+	cases := []struct {
+		doc    string
+		patch  string
+		result string
+	}{
+		{`{"a": "b"}`, `{"a": "c"}`, `{"a": "c"}`},
+		{`{"a": "b"}`, `{"b": "c"}`, `{"a": "b", "b": "c"}`},
+		{`{"a": "b"}`, `{"a": null}`, `{}`},
+		{`{"a": "b", "b": "c"}`, `{"a": null}`, `{"b": "c"}`},
+		{`{"a": ["b"]}`, `{"a": "c"}`, `{"a": "c"}`},
+		{`{"a": "c"}`, `{"a": ["b"]}`, `{"a": ["b"]}`},
+		{`{"a": {"b": "c"}}`, `{"a": {"b": "d", "c": null}}`, `{"a": {"b": "d"}}`},
+		{`{"a": [{"b": "c"}]}`, `{"a": [1]}`, `{"a": [1]}`},
+		{`["a", "b"]`, `["c", "d"]`, `["c", "d"]`},
+		{`{"a": "b"}`, `["c"]`, `["c"]`},
+		{`{"a": "foo"}`, `null`, `null`},
+		{`{"a": "foo"}`, `"bar"`, `"bar"`},
+		{`{"e": null}`, `{"a": 1}`, `{"e": null, "a": 1}`},
+		{`[1, 2]`, `{"a": "b", "c": null}`, `{"a": "b"}`},
+		{`{}`, `{"a": {"bb": {"ccc": null}}}`, `{"a": {"bb": {}}}`},
+	}
+
+	for _, c := range cases {
+		var doc, patch, result interface{}
+		if err := YamlUnmarshal([]byte(c.doc), &doc); err != nil {
+			t.Fatalf("Error reading document: %s", err)
+		}
+		if err := YamlUnmarshal([]byte(c.patch), &patch); err != nil {
+			t.Fatalf("Error reading patch: %s", err)
+		}
+		if err := YamlUnmarshal([]byte(c.result), &result); err != nil {
+			t.Fatalf("Error reading result: %s", err)
+		}
+		patched := ApplyJsonMergePatch(doc, patch)
+		if !jsonEqual(patched, result) {
+			t.Fatalf("Error matching patched document and result for patch %s: %#v vs %#v", c.patch, patched, result)
+		}
+	}
+}
@@ -2,11 +2,17 @@ package provider
 
 import (
 	"context"
+	"fmt"
+	"regexp"
 
 	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/ephemeral"
 	"github.com/hashicorp/terraform-plugin-framework/function"
+	"github.com/hashicorp/terraform-plugin-framework/path"
 	"github.com/hashicorp/terraform-plugin-framework/provider"
+	"github.com/hashicorp/terraform-plugin-framework/provider/schema"
 	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/types"
 )
 
 // provider satisfies the tfsdk.Provider interface and usually is included
@@ -28,15 +34,141 @@ func (p *utilsProvider) Metadata(_ context.Context, _ provider.MetadataRequest,
 	resp.TypeName = "utils"
 }
 
+// utilsProviderData is the configuration of the provider passed to data
+// sources, resources and ephemeral resources. Provider functions do not have
+// access to it.
+type utilsProviderData struct {
+	CustomTags []CustomTag
+	// Env are the variables of the env files of the provider.
+	Env map[string]string
+}
+
+type utilsProviderModel struct {
+	EnvFiles []string           `tfsdk:"env_files"`
+	Tags     []utilsProviderTag `tfsdk:"tag"`
+}
+
+type utilsProviderTag struct {
+	Name        types.String `tfsdk:"name"`
+	Lookup      types.Map    `tfsdk:"lookup"`
+	Regex       types.String `tfsdk:"regex"`
+	Replacement types.String `tfsdk:"replacement"`
+	Prefix      types.String `tfsdk:"prefix"`
+	Suffix      types.String `tfsdk:"suffix"`
+}
+
 func (p *utilsProvider) Schema(ctx context.Context, req provider.SchemaRequest, resp *provider.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Attributes: map[string]schema.Attribute{
+			"env_files": schema.ListAttribute{
+				MarkdownDescription: "A list of `.env` or Java `.properties` files with variables resolved by YAML `!env` tags, which take precedence over the environment of the provider. Variables of later files override variables of earlier files. Files ending in `.properties` are read as properties files, all other files as dotenv files. The env files are used by data sources, resources and ephemeral resources, but not by provider functions, which have an `env_files` option instead.",
+				ElementType:         types.StringType,
+				Optional:            true,
+			},
+		},
+		Blocks: map[string]schema.Block{
+			"tag": schema.ListNestedBlock{
+				MarkdownDescription: "Custom YAML tags, which transform scalar values. The lookup table is applied first, followed by the regular expression replacement and the prefix and suffix. Custom tags are supported by data sources, resources and ephemeral resources, but not by provider functions.",
+				NestedObject: schema.NestedBlockObject{
+					Attributes: map[string]schema.Attribute{
+						"name": schema.StringAttribute{
+							MarkdownDescription: "Name of the tag without the leading `!`, e.g. `site_prefix` for `!site_prefix` tags.",
+							Required:            true,
+						},
+						"lookup": schema.MapAttribute{
+							MarkdownDescription: "A lookup table used to replace values. Values not found in the lookup table are reported as error.",
+							ElementType:         types.StringType,
+							Optional:            true,
+						},
+						"regex": schema.StringAttribute{
+							MarkdownDescription: "A regular expression, where all matches are replaced by `replacement`.",
+							Optional:            true,
+						},
+						"replacement": schema.StringAttribute{
+							MarkdownDescription: "The replacement of `regex` matches, which can reference capture groups, e.g. `$1`.",
+							Optional:            true,
+						},
+						"prefix": schema.StringAttribute{
+							MarkdownDescription: "A prefix added to values.",
+							Optional:            true,
+						},
+						"suffix": schema.StringAttribute{
+							MarkdownDescription: "A suffix added to values.",
+							Optional:            true,
+						},
+					},
+				},
+			},
+		},
+	}
 }
 
 func (p *utilsProvider) Configure(ctx context.Context, req provider.ConfigureRequest, resp *provider.ConfigureResponse) {
+	var config utilsProviderModel
+
+	diags := req.Config.Get(ctx, &config)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	data := &utilsProviderData{}
+	for i, t := range config.Tags {
+		tag := CustomTag{
+			Name:        t.Name.ValueString(),
+			Replacement: t.Replacement.ValueString(),
+			Prefix:      t.Prefix.ValueString(),
+			Suffix:      t.Suffix.ValueString(),
+		}
+		if !customTagNameRegex.MatchString(tag.Name) || isBuiltinTag(tag.Tag()) {
+			resp.Diagnostics.AddAttributeError(
+				path.Root("tag").AtListIndex(i).AtName("name"),
+				"Invalid tag name",
+				fmt.Sprintf("Invalid tag name '%s', tag names must only contain letters, digits, `_` and `-` and must not be a builtin tag.", tag.Name),
+			)
+			continue
+		}
+		if !t.Lookup.IsNull() {
+			resp.Diagnostics.Append(t.Lookup.ElementsAs(ctx, &tag.Lookup, false)...)
+		}
+		if !t.Regex.IsNull() {
+			var err error
+			tag.Regex, err = regexp.Compile(t.Regex.ValueString())
+			if err != nil {
+				resp.Diagnostics.AddAttributeError(
+					path.Root("tag").AtListIndex(i).AtName("regex"),
+					"Invalid regular expression",
+					fmt.Sprintf("Invalid regular expression: %s", err),
+				)
+				continue
+			}
+		}
+		data.CustomTags = append(data.CustomTags, tag)
+	}
+
+	vars, err := LoadEnvFiles(config.EnvFiles)
+	if err != nil {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("env_files"),
+			"Error reading env files",
+			fmt.Sprintf("Error reading env files: %s", err),
+		)
+		return
+	}
+	data.Env = vars
+
+	resp.DataSourceData = data
+	resp.ResourceData = data
+	resp.EphemeralResourceData = data
 	p.configured = true
 }
 
+var customTagNameRegex = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
 func (p *utilsProvider) Resources(ctx context.Context) []func() resource.Resource {
-	return nil
+	return []func() resource.Resource{
+		NewYamlFileResource,
+	}
 }
 
 func (p *utilsProvider) DataSources(ctx context.Context) []func() datasource.DataSource {
@@ -45,9 +177,22 @@ func (p *utilsProvider) DataSources(ctx context.Context) []func() datasource.Dat
 	}
 }
 
+func (p *utilsProvider) EphemeralResources(ctx context.Context) []func() ephemeral.EphemeralResource {
+	return []func() ephemeral.EphemeralResource{
+		NewYamlMergeEphemeralResource,
+	}
+}
+
 func (p *utilsProvider) Functions(ctx context.Context) []func() function.Function {
 	return []func() function.Function{
+		NewHashFunction,
+		NewJsonToYamlFunction,
+		NewTomlToYamlFunction,
 		NewYamlMergeFunction,
+		NewYamlPatchFunction,
+		NewYamlQueryFunction,
+		NewYamlToJsonFunction,
+		NewYamlToTomlFunction,
 	}
 }
 
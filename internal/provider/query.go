@@ -0,0 +1,727 @@
+package provider
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Query is a parsed path expression in a subset of the jq/yq syntax, e.g.
+// `.tenants[] | select(.name == "prod") | .vrfs`. Supported are field
+// access (`.a`, `."a"`, `.["a"]`), indexing (`.[0]`, `.[-1]`), iteration
+// (`.[]`), pipes, `,`, `select()`, `has()`, `not`, `length`, `keys`,
+// comparisons, `and`/`or` and literals.
+type Query struct {
+	expr queryExpr
+}
+
+func ParseQuery(query string) (*Query, error) {
+	tokens, err := tokenizeQuery(query)
+	if err != nil {
+		return nil, err
+	}
+	p := &queryParser{tokens: tokens}
+	expr, err := p.parsePipe()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("unexpected '%s' at position %d", p.tokens[p.pos].value, p.tokens[p.pos].pos)
+	}
+	return &Query{expr: expr}, nil
+}
+
+// Run evaluates the query against a decoded YAML document and returns all
+// matching values.
+func (q *Query) Run(data interface{}) ([]interface{}, error) {
+	return q.expr.eval(data)
+}
+
+type queryTokenKind int
+
+const (
+	queryTokenPunct queryTokenKind = iota
+	queryTokenIdent
+	queryTokenString
+	queryTokenNumber
+)
+
+type queryToken struct {
+	kind  queryTokenKind
+	value string
+	pos   int
+}
+
+func tokenizeQuery(query string) ([]queryToken, error) {
+	var tokens []queryToken
+	for i := 0; i < len(query); {
+		c := query[i]
+		switch {
+		case unicode.IsSpace(rune(c)):
+			i++
+		case c == '"':
+			j := i + 1
+			for j < len(query) && query[j] != '"' {
+				if query[j] == '\\' {
+					j++
+				}
+				j++
+			}
+			if j >= len(query) {
+				return nil, fmt.Errorf("unterminated string at position %d", i)
+			}
+			s, err := strconv.Unquote(query[i : j+1])
+			if err != nil {
+				return nil, fmt.Errorf("invalid string at position %d: %s", i, err)
+			}
+			tokens = append(tokens, queryToken{queryTokenString, s, i})
+			i = j + 1
+		case unicode.IsDigit(rune(c)) || c == '-' && i+1 < len(query) && unicode.IsDigit(rune(query[i+1])):
+			j := i + 1
+			for j < len(query) && (unicode.IsDigit(rune(query[j])) || query[j] == '.' || query[j] == 'e') {
+				j++
+			}
+			tokens = append(tokens, queryToken{queryTokenNumber, query[i:j], i})
+			i = j
+		case c == '_' || unicode.IsLetter(rune(c)):
+			j := i + 1
+			for j < len(query) && (query[j] == '_' || query[j] == '-' || unicode.IsLetter(rune(query[j])) || unicode.IsDigit(rune(query[j]))) {
+				j++
+			}
+			tokens = append(tokens, queryToken{queryTokenIdent, query[i:j], i})
+			i = j
+		default:
+			matched := false
+			for _, op := range []string{"==", "!=", "<=", ">=", "<", ">", ".", "[", "]", "(", ")", "|", ","} {
+				if strings.HasPrefix(query[i:], op) {
+					tokens = append(tokens, queryToken{queryTokenPunct, op, i})
+					i += len(op)
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				return nil, fmt.Errorf("unexpected character '%c' at position %d", c, i)
+			}
+		}
+	}
+	return tokens, nil
+}
+
+type queryParser struct {
+	tokens []queryToken
+	pos    int
+}
+
+func (p *queryParser) peek(values ...string) bool {
+	if p.pos >= len(p.tokens) {
+		return false
+	}
+	t := p.tokens[p.pos]
+	if t.kind != queryTokenPunct && t.kind != queryTokenIdent {
+		return false
+	}
+	for _, v := range values {
+		if t.value == v {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *queryParser) expect(value string) error {
+	if !p.peek(value) {
+		if p.pos >= len(p.tokens) {
+			return fmt.Errorf("expected '%s' at end of query", value)
+		}
+		return fmt.Errorf("expected '%s' at position %d", value, p.tokens[p.pos].pos)
+	}
+	p.pos++
+	return nil
+}
+
+func (p *queryParser) parsePipe() (queryExpr, error) {
+	left, err := p.parseComma()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek("|") {
+		p.pos++
+		right, err := p.parseComma()
+		if err != nil {
+			return nil, err
+		}
+		left = &queryPipe{left, right}
+	}
+	return left, nil
+}
+
+func (p *queryParser) parseComma() (queryExpr, error) {
+	left, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek(",") {
+		p.pos++
+		right, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		left = &queryComma{left, right}
+	}
+	return left, nil
+}
+
+func (p *queryParser) parseOr() (queryExpr, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek("or") {
+		p.pos++
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &queryBinary{"or", left, right}
+	}
+	return left, nil
+}
+
+func (p *queryParser) parseAnd() (queryExpr, error) {
+	left, err := p.parseCompare()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek("and") {
+		p.pos++
+		right, err := p.parseCompare()
+		if err != nil {
+			return nil, err
+		}
+		left = &queryBinary{"and", left, right}
+	}
+	return left, nil
+}
+
+func (p *queryParser) parseCompare() (queryExpr, error) {
+	left, err := p.parsePostfix()
+	if err != nil {
+		return nil, err
+	}
+	if p.peek("==", "!=", "<", "<=", ">", ">=") {
+		op := p.tokens[p.pos].value
+		p.pos++
+		right, err := p.parsePostfix()
+		if err != nil {
+			return nil, err
+		}
+		return &queryBinary{op, left, right}, nil
+	}
+	return left, nil
+}
+
+// parsePostfix parses a primary expression followed by any number of path
+// components, which allows paths to continue after e.g. `select()`.
+func (p *queryParser) parsePostfix() (queryExpr, error) {
+	expr, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if p.peek("[") {
+			expr, err = p.parseBracket(expr)
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if p.peek(".") && p.pos+1 < len(p.tokens) {
+			next := p.tokens[p.pos+1]
+			if next.kind == queryTokenIdent || next.kind == queryTokenString {
+				p.pos += 2
+				expr = &queryField{expr, next.value}
+				continue
+			}
+			if next.kind == queryTokenPunct && next.value == "[" {
+				p.pos++
+				continue
+			}
+		}
+		return expr, nil
+	}
+}
+
+func (p *queryParser) parseBracket(target queryExpr) (queryExpr, error) {
+	p.pos++
+	if p.peek("]") {
+		p.pos++
+		return &queryIterate{target}, nil
+	}
+	if p.pos >= len(p.tokens) {
+		return nil, fmt.Errorf("unexpected end of query")
+	}
+	t := p.tokens[p.pos]
+	var expr queryExpr
+	switch t.kind {
+	case queryTokenString:
+		expr = &queryField{target, t.value}
+	case queryTokenNumber:
+		index, err := strconv.Atoi(t.value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid index '%s' at position %d", t.value, t.pos)
+		}
+		expr = &queryIndex{target, index}
+	default:
+		return nil, fmt.Errorf("unexpected '%s' at position %d", t.value, t.pos)
+	}
+	p.pos++
+	return expr, p.expect("]")
+}
+
+func (p *queryParser) parsePrimary() (queryExpr, error) {
+	if p.pos >= len(p.tokens) {
+		return nil, fmt.Errorf("unexpected end of query")
+	}
+	t := p.tokens[p.pos]
+	p.pos++
+	switch t.kind {
+	case queryTokenString:
+		return &queryLiteral{t.value}, nil
+	case queryTokenNumber:
+		if i, err := strconv.Atoi(t.value); err == nil {
+			return &queryLiteral{i}, nil
+		}
+		f, err := strconv.ParseFloat(t.value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number '%s' at position %d", t.value, t.pos)
+		}
+		return &queryLiteral{f}, nil
+	case queryTokenIdent:
+		switch t.value {
+		case "true":
+			return &queryLiteral{true}, nil
+		case "false":
+			return &queryLiteral{false}, nil
+		case "null":
+			return &queryLiteral{nil}, nil
+		case "not", "length", "keys":
+			return &queryFunc{t.value, nil}, nil
+		case "select", "has":
+			if err := p.expect("("); err != nil {
+				return nil, err
+			}
+			arg, err := p.parsePipe()
+			if err != nil {
+				return nil, err
+			}
+			return &queryFunc{t.value, arg}, p.expect(")")
+		}
+		return nil, fmt.Errorf("unknown function '%s' at position %d", t.value, t.pos)
+	}
+	switch t.value {
+	case "(":
+		expr, err := p.parsePipe()
+		if err != nil {
+			return nil, err
+		}
+		return expr, p.expect(")")
+	case ".":
+		if p.pos < len(p.tokens) {
+			next := p.tokens[p.pos]
+			if next.kind == queryTokenIdent || next.kind == queryTokenString {
+				p.pos++
+				return &queryField{&queryIdentity{}, next.value}, nil
+			}
+		}
+		return &queryIdentity{}, nil
+	}
+	return nil, fmt.Errorf("unexpected '%s' at position %d", t.value, t.pos)
+}
+
+type queryExpr interface {
+	eval(input interface{}) ([]interface{}, error)
+}
+
+type queryIdentity struct{}
+
+func (e *queryIdentity) eval(input interface{}) ([]interface{}, error) {
+	return []interface{}{input}, nil
+}
+
+type queryLiteral struct {
+	value interface{}
+}
+
+func (e *queryLiteral) eval(input interface{}) ([]interface{}, error) {
+	return []interface{}{e.value}, nil
+}
+
+type queryPipe struct {
+	left, right queryExpr
+}
+
+func (e *queryPipe) eval(input interface{}) ([]interface{}, error) {
+	left, err := e.left.eval(input)
+	if err != nil {
+		return nil, err
+	}
+	var results []interface{}
+	for _, l := range left {
+		r, err := e.right.eval(l)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, r...)
+	}
+	return results, nil
+}
+
+type queryComma struct {
+	left, right queryExpr
+}
+
+func (e *queryComma) eval(input interface{}) ([]interface{}, error) {
+	left, err := e.left.eval(input)
+	if err != nil {
+		return nil, err
+	}
+	right, err := e.right.eval(input)
+	if err != nil {
+		return nil, err
+	}
+	return append(left, right...), nil
+}
+
+type queryField struct {
+	target queryExpr
+	name   string
+}
+
+func (e *queryField) eval(input interface{}) ([]interface{}, error) {
+	targets, err := e.target.eval(input)
+	if err != nil {
+		return nil, err
+	}
+	results := make([]interface{}, 0, len(targets))
+	for _, t := range targets {
+		if t == nil {
+			results = append(results, nil)
+			continue
+		}
+		value := reflect.ValueOf(t)
+		if value.Kind() != reflect.Map {
+			return nil, fmt.Errorf("cannot index %s with \"%s\"", queryTypeName(t), e.name)
+		}
+		results = append(results, mapIndex(value, e.name))
+	}
+	return results, nil
+}
+
+type queryIndex struct {
+	target queryExpr
+	index  int
+}
+
+func (e *queryIndex) eval(input interface{}) ([]interface{}, error) {
+	targets, err := e.target.eval(input)
+	if err != nil {
+		return nil, err
+	}
+	results := make([]interface{}, 0, len(targets))
+	for _, t := range targets {
+		if t == nil {
+			results = append(results, nil)
+			continue
+		}
+		value := reflect.ValueOf(t)
+		if value.Kind() != reflect.Slice {
+			return nil, fmt.Errorf("cannot index %s with number", queryTypeName(t))
+		}
+		index := e.index
+		if index < 0 {
+			index += value.Len()
+		}
+		if index < 0 || index >= value.Len() {
+			results = append(results, nil)
+			continue
+		}
+		results = append(results, value.Index(index).Interface())
+	}
+	return results, nil
+}
+
+type queryIterate struct {
+	target queryExpr
+}
+
+func (e *queryIterate) eval(input interface{}) ([]interface{}, error) {
+	targets, err := e.target.eval(input)
+	if err != nil {
+		return nil, err
+	}
+	var results []interface{}
+	for _, t := range targets {
+		if t == nil {
+			continue
+		}
+		value := reflect.ValueOf(t)
+		switch value.Kind() {
+		case reflect.Slice:
+			for i := 0; i < value.Len(); i++ {
+				results = append(results, value.Index(i).Interface())
+			}
+		case reflect.Map:
+			for _, key := range sortedMapKeys(value) {
+				results = append(results, value.MapIndex(key).Interface())
+			}
+		default:
+			return nil, fmt.Errorf("cannot iterate over %s", queryTypeName(t))
+		}
+	}
+	return results, nil
+}
+
+type queryFunc struct {
+	name string
+	arg  queryExpr
+}
+
+func (e *queryFunc) eval(input interface{}) ([]interface{}, error) {
+	switch e.name {
+	case "select":
+		conditions, err := e.arg.eval(input)
+		if err != nil {
+			return nil, err
+		}
+		var results []interface{}
+		for _, c := range conditions {
+			if queryTruthy(c) {
+				results = append(results, input)
+			}
+		}
+		return results, nil
+	case "has":
+		keys, err := e.arg.eval(input)
+		if err != nil {
+			return nil, err
+		}
+		var results []interface{}
+		for _, key := range keys {
+			value := reflect.ValueOf(input)
+			switch value.Kind() {
+			case reflect.Map:
+				results = append(results, mapHasKey(value, key))
+			case reflect.Slice:
+				i, ok := key.(int)
+				results = append(results, ok && i >= 0 && i < value.Len())
+			default:
+				return nil, fmt.Errorf("cannot check whether %s has a key", queryTypeName(input))
+			}
+		}
+		return results, nil
+	case "not":
+		return []interface{}{!queryTruthy(input)}, nil
+	case "length":
+		if input == nil {
+			return []interface{}{0}, nil
+		}
+		value := reflect.ValueOf(input)
+		switch value.Kind() {
+		case reflect.String:
+			return []interface{}{len([]rune(value.String()))}, nil
+		case reflect.Slice, reflect.Map:
+			return []interface{}{value.Len()}, nil
+		}
+		if f, ok := queryNumber(input); ok {
+			return []interface{}{math.Abs(f)}, nil
+		}
+		return nil, fmt.Errorf("%s has no length", queryTypeName(input))
+	case "keys":
+		value := reflect.ValueOf(input)
+		switch value.Kind() {
+		case reflect.Map:
+			keys := []interface{}{}
+			for _, key := range sortedMapKeys(value) {
+				keys = append(keys, key.Interface())
+			}
+			return []interface{}{keys}, nil
+		case reflect.Slice:
+			keys := []interface{}{}
+			for i := 0; i < value.Len(); i++ {
+				keys = append(keys, i)
+			}
+			return []interface{}{keys}, nil
+		}
+		return nil, fmt.Errorf("%s has no keys", queryTypeName(input))
+	}
+	return nil, fmt.Errorf("unknown function '%s'", e.name)
+}
+
+type queryBinary struct {
+	op          string
+	left, right queryExpr
+}
+
+func (e *queryBinary) eval(input interface{}) ([]interface{}, error) {
+	left, err := e.left.eval(input)
+	if err != nil {
+		return nil, err
+	}
+	right, err := e.right.eval(input)
+	if err != nil {
+		return nil, err
+	}
+	var results []interface{}
+	for _, r := range right {
+		for _, l := range left {
+			var result bool
+			switch e.op {
+			case "and":
+				result = queryTruthy(l) && queryTruthy(r)
+			case "or":
+				result = queryTruthy(l) || queryTruthy(r)
+			case "==":
+				result = queryCompare(l, r) == 0
+			case "!=":
+				result = queryCompare(l, r) != 0
+			case "<":
+				result = queryCompare(l, r) < 0
+			case "<=":
+				result = queryCompare(l, r) <= 0
+			case ">":
+				result = queryCompare(l, r) > 0
+			case ">=":
+				result = queryCompare(l, r) >= 0
+			}
+			results = append(results, result)
+		}
+	}
+	return results, nil
+}
+
+func queryTruthy(v interface{}) bool {
+	if v == nil {
+		return false
+	}
+	if b, ok := v.(bool); ok {
+		return b
+	}
+	return true
+}
+
+func queryNumber(v interface{}) (float64, bool) {
+	value := reflect.ValueOf(v)
+	switch value.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(value.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(value.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return value.Float(), true
+	}
+	return 0, false
+}
+
+// queryTypeRank orders values of different types like jq does:
+// null < false < true < numbers < strings < arrays < objects.
+func queryTypeRank(v interface{}) int {
+	if v == nil {
+		return 0
+	}
+	if b, ok := v.(bool); ok {
+		if b {
+			return 2
+		}
+		return 1
+	}
+	if _, ok := queryNumber(v); ok {
+		return 3
+	}
+	switch reflect.ValueOf(v).Kind() {
+	case reflect.String:
+		return 4
+	case reflect.Slice:
+		return 5
+	case reflect.Map:
+		return 6
+	}
+	return 7
+}
+
+func queryCompare(a, b interface{}) int {
+	ra, rb := queryTypeRank(a), queryTypeRank(b)
+	if ra != rb {
+		return ra - rb
+	}
+	switch ra {
+	case 3:
+		fa, _ := queryNumber(a)
+		fb, _ := queryNumber(b)
+		if fa < fb {
+			return -1
+		} else if fa > fb {
+			return 1
+		}
+		return 0
+	case 4:
+		return strings.Compare(reflect.ValueOf(a).String(), reflect.ValueOf(b).String())
+	}
+	if reflect.DeepEqual(a, b) {
+		return 0
+	}
+	return strings.Compare(fmt.Sprint(a), fmt.Sprint(b))
+}
+
+func queryTypeName(v interface{}) string {
+	switch queryTypeRank(v) {
+	case 0:
+		return "null"
+	case 1, 2:
+		return "boolean"
+	case 3:
+		return "number"
+	case 4:
+		return "string"
+	case 5:
+		return "array"
+	case 6:
+		return "object"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+// mapIndex returns the value of a decoded YAML map for key, regardless of the
+// map's key type.
+func mapIndex(m reflect.Value, key interface{}) interface{} {
+	k := reflect.ValueOf(key)
+	if !k.IsValid() || !k.Type().AssignableTo(m.Type().Key()) {
+		return nil
+	}
+	v := m.MapIndex(k)
+	if !v.IsValid() {
+		return nil
+	}
+	return v.Interface()
+}
+
+func mapHasKey(m reflect.Value, key interface{}) bool {
+	k := reflect.ValueOf(key)
+	if !k.IsValid() || !k.Type().AssignableTo(m.Type().Key()) {
+		return false
+	}
+	return m.MapIndex(k).IsValid()
+}
+
+// sortedMapKeys returns the keys of a map in a stable order.
+func sortedMapKeys(m reflect.Value) []reflect.Value {
+	keys := m.MapKeys()
+	sort.Slice(keys, func(i, j int) bool {
+		return fmt.Sprint(keys[i].Interface()) < fmt.Sprint(keys[j].Interface())
+	})
+	return keys
+}
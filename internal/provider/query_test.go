@@ -0,0 +1,123 @@
+package provider
+
+import (
+	"reflect"
+	"testing"
+)
+
+const queryTestYaml = `
+tenants:
+  - name: prod
+    vrfs:
+      - name: v1
+      - name: v2
+  - name: dev
+    vrfs:
+      - name: v3
+    enabled: false
+"key with space": 1
+`
+
+func TestQuery(t *testing.T) {
+	var data interface{}
+	err := YamlUnmarshal([]byte(queryTestYaml), &data)
+	if err != nil {
+		t.Fatalf("Error reading YAML string: %s", err)
+	}
+
+	cases := []struct {
+		query  string
+		result []interface{}
+	}{
+		{
+			query:  `.tenants[0].name`,
+			result: []interface{}{"prod"},
+		},
+		{
+			query:  `.tenants[-1].name`,
+			result: []interface{}{"dev"},
+		},
+		{
+			query:  `.tenants[].name`,
+			result: []interface{}{"prod", "dev"},
+		},
+		{
+			query: `.tenants[] | select(.name == "prod") .vrfs`,
+			result: []interface{}{
+				[]interface{}{
+					map[string]interface{}{"name": "v1"},
+					map[string]interface{}{"name": "v2"},
+				},
+			},
+		},
+		{
+			query:  `.tenants[] | select(.name != "prod" and has("enabled")) | .vrfs[].name`,
+			result: []interface{}{"v3"},
+		},
+		{
+			query:  `.tenants[] | select(.enabled | not) | .name`,
+			result: []interface{}{"prod", "dev"},
+		},
+		{
+			query:  `.tenants | length`,
+			result: []interface{}{2},
+		},
+		{
+			query:  `.["key with space"], ."key with space"`,
+			result: []interface{}{1, 1},
+		},
+		{
+			query:  `.missing.child`,
+			result: []interface{}{nil},
+		},
+		{
+			query:  `. | keys`,
+			result: []interface{}{[]interface{}{"key with space", "tenants"}},
+		},
+		{
+			query:  `.tenants[] | select(.vrfs | length > 1) | .name`,
+			result: []interface{}{"prod"},
+		},
+	}
+
+	for _, c := range cases {
+		q, err := ParseQuery(c.query)
+		if err != nil {
+			t.Fatalf("Error parsing query %s: %s", c.query, err)
+		}
+		result, err := q.Run(data)
+		if err != nil {
+			t.Fatalf("Error running query %s: %s", c.query, err)
+		}
+		if !reflect.DeepEqual(result, c.result) {
+			t.Fatalf("Error matching query %s: %#v vs %#v", c.query, result, c.result)
+		}
+	}
+}
+
+func TestQueryErrors(t *testing.T) {
+	var data interface{}
+	err := YamlUnmarshal([]byte(queryTestYaml), &data)
+	if err != nil {
+		t.Fatalf("Error reading YAML string: %s", err)
+	}
+
+	cases := []string{
+		`.tenants[`,
+		`.tenants | unknown`,
+		`select(.name`,
+		`.tenants.name`,
+		`.tenants[0].name[]`,
+		`.tenants ! 1`,
+	}
+
+	for _, c := range cases {
+		q, err := ParseQuery(c)
+		if err == nil {
+			_, err = q.Run(data)
+		}
+		if err == nil {
+			t.Fatalf("Expected error for query %s", c)
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package provider
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// refValue is the value of a `!ref` tag until it is resolved by ResolveRefs.
+type refValue struct {
+	path string
+}
+
+func (i *CustomTagProcessor) resolveRef(node *yaml.Node) (*yaml.Node, error) {
+	if node.Kind != yaml.ScalarNode {
+		return nil, errors.New("!ref on a non-scalar node")
+	}
+	if node.Value == "" {
+		return nil, errors.New("!ref with an empty path")
+	}
+	i.mark(node, refValue{path: node.Value})
+	return node, nil
+}
+
+// ResolveRefs replaces all values decoded from `!ref path.to.value` tags with
+// a copy of the value at the referenced path of root. Path elements are
+// separated by dots, list items are referenced by their index.
+func ResolveRefs(root interface{}) (interface{}, error) {
+	r := &refResolver{root: root, resolving: map[string]bool{}}
+	return r.resolve(root)
+}
+
+type refResolver struct {
+	root      interface{}
+	resolving map[string]bool
+	stack     []string
+}
+
+func (r *refResolver) resolve(v interface{}) (interface{}, error) {
+	if path, ok := refPath(v); ok {
+		return r.lookup(path)
+	}
+	if s, ok := v.(interpolation); ok {
+		return r.interpolate(s)
+	}
+	value := reflect.ValueOf(v)
+	switch value.Kind() {
+	case reflect.Map:
+		iter := value.MapRange()
+		for iter.Next() {
+			resolved, err := r.resolve(iter.Value().Interface())
+			if err != nil {
+				return nil, err
+			}
+			value.SetMapIndex(iter.Key(), reflectValue(resolved, value.Type().Elem()))
+		}
+	case reflect.Slice:
+		for i := 0; i < value.Len(); i++ {
+			resolved, err := r.resolve(value.Index(i).Interface())
+			if err != nil {
+				return nil, err
+			}
+			value.Index(i).Set(reflectValue(resolved, value.Type().Elem()))
+		}
+	}
+	return v, nil
+}
+
+// lookup returns a resolved copy of the value at path.
+func (r *refResolver) lookup(path string) (interface{}, error) {
+	if r.resolving[path] {
+		return nil, fmt.Errorf("!ref %s: reference cycle %s -> %s", r.stack[0], strings.Join(r.stack, " -> "), path)
+	}
+	r.resolving[path] = true
+	r.stack = append(r.stack, path)
+	defer func() {
+		delete(r.resolving, path)
+		r.stack = r.stack[:len(r.stack)-1]
+	}()
+
+	v := r.root
+	for _, token := range strings.Split(path, ".") {
+		// intermediate values might be references themselves
+		if p, ok := refPath(v); ok {
+			var err error
+			v, err = r.lookup(p)
+			if err != nil {
+				return nil, err
+			}
+		}
+		var err error
+		v, err = jsonPatchGet(v, []string{token})
+		if err != nil {
+			return nil, fmt.Errorf("!ref %s: %s", path, err)
+		}
+	}
+	return r.resolve(deepCopy(v))
+}
+
+// interpolate resolves `${ref:path}` expressions of a string marked by
+// CustomTagProcessor.interpolate.
+func (r *refResolver) interpolate(s interpolation) (interface{}, error) {
+	result, err := interpolateString(s.template, false, func(expr string) (string, error) {
+		value, err := r.lookup(strings.TrimPrefix(expr, "ref:"))
+		if err != nil {
+			return "", err
+		}
+		switch reflect.ValueOf(value).Kind() {
+		case reflect.Map, reflect.Slice:
+			return "", fmt.Errorf("${%s} is not a scalar value", expr)
+		case reflect.Invalid:
+			return "", nil
+		}
+		return fmt.Sprint(value), nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", s.location, err)
+	}
+	return result, nil
+}
+
+func refPath(v interface{}) (string, bool) {
+	ref, ok := v.(refValue)
+	return ref.path, ok
+}
@@ -0,0 +1,161 @@
+package provider
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestResolveRefs(t *testing.T) {
+	cases := []struct {
+		input  string
+		result map[string]interface{}
+	}{
+		// scalar reference
+		{
+			input: `
+tenant: tenant1
+vrf:
+  tenant: !ref tenant
+`,
+			result: map[string]interface{}{
+				"tenant": "tenant1",
+				"vrf":    map[string]interface{}{"tenant": "tenant1"},
+			},
+		},
+		// list index and map reference
+		{
+			input: `
+tenants:
+  - name: tenant1
+    vrfs: [vrf1]
+first: !ref tenants.0
+`,
+			result: map[string]interface{}{
+				"tenants": []interface{}{map[string]interface{}{"name": "tenant1", "vrfs": []interface{}{"vrf1"}}},
+				"first":   map[string]interface{}{"name": "tenant1", "vrfs": []interface{}{"vrf1"}},
+			},
+		},
+		// chained references
+		{
+			input: `
+elem1: !ref elem2
+elem2: !ref elem3.child
+elem3: !ref elem4
+elem4:
+  child: value
+`,
+			result: map[string]interface{}{
+				"elem1": "value",
+				"elem2": "value",
+				"elem3": map[string]interface{}{"child": "value"},
+				"elem4": map[string]interface{}{"child": "value"},
+			},
+		},
+		// strings looking like references are not resolved
+		{
+			input: `
+a: "\0!ref:b"
+b: value
+`,
+			result: map[string]interface{}{
+				"a": "\x00!ref:b",
+				"b": "value",
+			},
+		},
+		// references of merged maps
+		{
+			input: `
+base: &base
+  name: !ref b
+b: value
+other:
+  <<: *base
+`,
+			result: map[string]interface{}{
+				"base":  map[string]interface{}{"name": "value"},
+				"b":     "value",
+				"other": map[string]interface{}{"name": "value"},
+			},
+		},
+		// integer keys
+		{
+			input: `
+vlans:
+  10: vlan10
+name: !ref vlans.10
+`,
+			result: map[string]interface{}{
+				"vlans": map[interface{}]interface{}{10: "vlan10"},
+				"name":  "vlan10",
+			},
+		},
+	}
+
+	for _, c := range cases {
+		var data map[string]interface{}
+		err := YamlUnmarshal([]byte(c.input), &data)
+		if err != nil {
+			t.Fatalf("Error reading YAML string: %s", err)
+		}
+		_, err = ResolveRefs(data)
+		if err != nil {
+			t.Fatalf("Error resolving references: %s", err)
+		}
+		if !reflect.DeepEqual(data, c.result) {
+			t.Fatalf("Error matching data and result: %#v vs %#v", data, c.result)
+		}
+	}
+}
+
+func TestResolveRefsJson(t *testing.T) {
+	var data interface{}
+	err := UnmarshalWithFormat("json", []byte(`{"a": "\u0000!ref:b", "b": "value"}`), &data, YamlOptions{})
+	if err != nil {
+		t.Fatalf("Error reading JSON string: %s", err)
+	}
+	_, err = ResolveRefs(data)
+	if err != nil {
+		t.Fatalf("Error resolving references: %s", err)
+	}
+	result := map[string]interface{}{"a": "\x00!ref:b", "b": "value"}
+	if !reflect.DeepEqual(data, result) {
+		t.Fatalf("Error matching data and result: %#v vs %#v", data, result)
+	}
+}
+
+func TestResolveRefsErrors(t *testing.T) {
+	cases := []struct {
+		input string
+		err   string
+	}{
+		{
+			input: "elem1: !ref elem2\n",
+			err:   "!ref elem2: path 'elem2' does not exist",
+		},
+		{
+			input: "elem1: !ref elem2.child\nelem2: value\n",
+			err:   "!ref elem2.child: path 'child' does not exist",
+		},
+		{
+			input: "elem1: !ref elem2\nelem2: !ref elem1\n",
+			err:   "reference cycle",
+		},
+		{
+			input: "elem1:\n  child: !ref elem1\n",
+			err:   "reference cycle elem1 -> elem1",
+		},
+	}
+
+	for _, c := range cases {
+		var data map[string]interface{}
+		err := YamlUnmarshal([]byte(c.input), &data)
+		if err != nil {
+			t.Fatalf("Error reading YAML string: %s", err)
+		}
+		_, err = ResolveRefs(data)
+		if err == nil || !strings.Contains(err.Error(), c.err) {
+			t.Fatalf("Error matching error: %v vs %s", err, c.err)
+		}
+	}
+}
@@ -0,0 +1,308 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringdefault"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+var _ resource.Resource = (*yamlFileResource)(nil)
+var _ resource.ResourceWithModifyPlan = (*yamlFileResource)(nil)
+var _ resource.ResourceWithConfigure = (*yamlFileResource)(nil)
+
+func NewYamlFileResource() resource.Resource {
+	return &yamlFileResource{}
+}
+
+type yamlFileResource struct {
+	data *utilsProviderData
+}
+
+func (r *yamlFileResource) Configure(_ context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+	r.data = req.ProviderData.(*utilsProviderData)
+}
+
+func (r *yamlFileResource) Metadata(_ context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_yaml_file"
+}
+
+func (r *yamlFileResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
+	attributes := yamlMergeAttributes(resourceAttributes)
+	attributes["filename"] = schema.StringAttribute{
+		Description: "The path of the file the output is written to. Missing parent directories are created.",
+		Required:    true,
+		PlanModifiers: []planmodifier.String{
+			stringplanmodifier.RequiresReplace(),
+		},
+	}
+	attributes["file_permission"] = schema.StringAttribute{
+		Description: "Permissions of the file as four digit octal number. Default value is `0644`.",
+		Optional:    true,
+		Computed:    true,
+		Default:     stringdefault.StaticString("0644"),
+	}
+	attributes["directory_permission"] = schema.StringAttribute{
+		Description: "Permissions of created parent directories as four digit octal number. Default value is `0777`.",
+		Optional:    true,
+		Computed:    true,
+		Default:     stringdefault.StaticString("0777"),
+	}
+
+	resp.Schema = schema.Schema{
+		// This description is used by the documentation generator and the language server.
+		MarkdownDescription: "Merge a list of YAML strings like the `utils_yaml_merge` data source and write the output to a file. The output is rendered during plan, so changes of the file are shown in the plan, and changes of the file or its permissions outside of Terraform are detected and reverted.",
+
+		Attributes: attributes,
+	}
+}
+
+// resourceAttributes builds resource attributes.
+var resourceAttributes = attributeBuilder[schema.Attribute]{
+	String: func(s attributeSpec) schema.Attribute {
+		return schema.StringAttribute{Description: s.Description, Required: s.Required, Optional: s.Optional, Computed: s.Computed}
+	},
+	Bool: func(s attributeSpec) schema.Attribute {
+		return schema.BoolAttribute{Description: s.Description, Required: s.Required, Optional: s.Optional, Computed: s.Computed}
+	},
+	Int64: func(s attributeSpec) schema.Attribute {
+		return schema.Int64Attribute{Description: s.Description, Required: s.Required, Optional: s.Optional, Computed: s.Computed}
+	},
+	List: func(s attributeSpec, elementType attr.Type) schema.Attribute {
+		return schema.ListAttribute{Description: s.Description, ElementType: elementType, Required: s.Required, Optional: s.Optional, Computed: s.Computed}
+	},
+	Map: func(s attributeSpec, elementType attr.Type) schema.Attribute {
+		return schema.MapAttribute{Description: s.Description, ElementType: elementType, Required: s.Required, Optional: s.Optional, Computed: s.Computed}
+	},
+	Dynamic: func(s attributeSpec) schema.Attribute {
+		return schema.DynamicAttribute{Description: s.Description, Required: s.Required, Optional: s.Optional, Computed: s.Computed}
+	},
+}
+
+type YamlFile struct {
+	Id                  types.String `tfsdk:"id"`
+	Filename            types.String `tfsdk:"filename"`
+	FilePermission      types.String `tfsdk:"file_permission"`
+	DirectoryPermission types.String `tfsdk:"directory_permission"`
+	YamlMergeBase
+}
+
+// render merges the inputs and sets the output and the id.
+func (m *YamlFile) render(ctx context.Context, data *utilsProviderData) diag.Diagnostics {
+	hash, diags := m.read(ctx, data)
+	if diags.HasError() {
+		return diags
+	}
+	m.Id = types.StringValue(hash)
+	return diags
+}
+
+func (r *yamlFileResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	// nothing to render if the resource is destroyed
+	if req.Plan.Raw.IsNull() {
+		return
+	}
+
+	// the output is unknown until all inputs are known
+	if !req.Config.Raw.IsFullyKnown() {
+		resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("output"), types.StringUnknown())...)
+		resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("id"), types.StringUnknown())...)
+		return
+	}
+
+	var plan YamlFile
+	diags := req.Plan.Get(ctx, &plan)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	resp.Diagnostics.Append(plan.validatePermissions()...)
+	resp.Diagnostics.Append(plan.render(ctx, r.data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	diags = resp.Plan.Set(ctx, &plan)
+	resp.Diagnostics.Append(diags...)
+}
+
+func (r *yamlFileResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	var plan YamlFile
+
+	diags := req.Plan.Get(ctx, &plan)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	resp.Diagnostics.Append(plan.write(ctx, r.data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	diags = resp.State.Set(ctx, &plan)
+	resp.Diagnostics.Append(diags...)
+}
+
+func (r *yamlFileResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	var state YamlFile
+
+	diags := req.State.Get(ctx, &state)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// a changed output or permission is reverted by the next apply, a
+	// missing file is created again
+	filename := state.Filename.ValueString()
+	content, err := os.ReadFile(filename)
+	if errors.Is(err, fs.ErrNotExist) {
+		resp.State.RemoveResource(ctx)
+		return
+	}
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Error reading file",
+			fmt.Sprintf("Error reading file: %s", err),
+		)
+		return
+	}
+	info, err := os.Stat(filename)
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Error reading file",
+			fmt.Sprintf("Error reading file: %s", err),
+		)
+		return
+	}
+	if string(content) != state.Output.ValueString() {
+		state.Output = types.StringValue(string(content))
+	}
+	state.FilePermission = types.StringValue(fmt.Sprintf("%04o", info.Mode().Perm()))
+
+	diags = resp.State.Set(ctx, &state)
+	resp.Diagnostics.Append(diags...)
+}
+
+func (r *yamlFileResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	var plan YamlFile
+
+	diags := req.Plan.Get(ctx, &plan)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	resp.Diagnostics.Append(plan.write(ctx, r.data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	diags = resp.State.Set(ctx, &plan)
+	resp.Diagnostics.Append(diags...)
+}
+
+func (r *yamlFileResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	var state YamlFile
+
+	diags := req.State.Get(ctx, &state)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	err := os.Remove(state.Filename.ValueString())
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		resp.Diagnostics.AddError(
+			"Error deleting file",
+			fmt.Sprintf("Error deleting file: %s", err),
+		)
+	}
+}
+
+var permissionRegex = regexp.MustCompile(`^0[0-7]{3}$`)
+
+func (m *YamlFile) validatePermissions() diag.Diagnostics {
+	var diags diag.Diagnostics
+	if !m.FilePermission.IsUnknown() && !permissionRegex.MatchString(m.FilePermission.ValueString()) {
+		diags.AddAttributeError(
+			path.Root("file_permission"),
+			"Invalid file permission",
+			fmt.Sprintf("Invalid file permission '%s', must be a four digit octal number, e.g. `0644`.", m.FilePermission.ValueString()),
+		)
+	}
+	if !m.DirectoryPermission.IsUnknown() && !permissionRegex.MatchString(m.DirectoryPermission.ValueString()) {
+		diags.AddAttributeError(
+			path.Root("directory_permission"),
+			"Invalid directory permission",
+			fmt.Sprintf("Invalid directory permission '%s', must be a four digit octal number, e.g. `0755`.", m.DirectoryPermission.ValueString()),
+		)
+	}
+	return diags
+}
+
+// write renders the output if it is unknown and writes it to the file.
+func (m *YamlFile) write(ctx context.Context, data *utilsProviderData) diag.Diagnostics {
+	diags := m.validatePermissions()
+	if diags.HasError() {
+		return diags
+	}
+	if m.Output.IsUnknown() {
+		diags.Append(m.render(ctx, data)...)
+		if diags.HasError() {
+			return diags
+		}
+	}
+
+	err := WriteFile(m.Filename.ValueString(), []byte(m.Output.ValueString()), m.FilePermission.ValueString(), m.DirectoryPermission.ValueString())
+	if err != nil {
+		diags.AddError(
+			"Error writing file",
+			fmt.Sprintf("Error writing file: %s", err),
+		)
+	}
+	return diags
+}
+
+// WriteFile writes data to a file with the given octal permissions and
+// creates missing parent directories. The permissions of an existing file
+// are changed as well.
+func WriteFile(filename string, data []byte, filePermission, directoryPermission string) error {
+	fileMode, err := strconv.ParseUint(filePermission, 8, 32)
+	if err != nil {
+		return err
+	}
+	directoryMode, err := strconv.ParseUint(directoryPermission, 8, 32)
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(filepath.Dir(filename), os.FileMode(directoryMode))
+	if err != nil {
+		return err
+	}
+	err = os.WriteFile(filename, data, os.FileMode(fileMode))
+	if err != nil {
+		return err
+	}
+	// the umask applies to new files and existing files keep their permissions
+	return os.Chmod(filename, os.FileMode(fileMode))
+}
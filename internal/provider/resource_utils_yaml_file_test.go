@@ -0,0 +1,145 @@
+package provider
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+	"github.com/hashicorp/terraform-plugin-testing/terraform"
+)
+
+func TestAccResourceUtilsYamlFile(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "dir", "output.yaml")
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		CheckDestroy:             testAccCheckYamlFileDestroyed(filename),
+		Steps: []resource.TestStep{
+			{
+				Config: testAccResourceUtilsYamlFile_config(filename, "b: 2"),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("utils_yaml_file.test", "output", yamlFile_outputYaml),
+					resource.TestCheckResourceAttr("utils_yaml_file.test", "file_permission", "0640"),
+					testAccCheckYamlFile(filename, yamlFile_outputYaml, 0640),
+				),
+			},
+			{
+				// changes outside of Terraform are reverted
+				PreConfig: func() {
+					err := os.WriteFile(filename, []byte("edited: true\n"), 0600)
+					if err == nil {
+						err = os.Chmod(filename, 0600)
+					}
+					if err != nil {
+						t.Fatalf("Error editing file: %s", err)
+					}
+				},
+				Config: testAccResourceUtilsYamlFile_config(filename, "b: 2"),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckYamlFile(filename, yamlFile_outputYaml, 0640),
+				),
+			},
+			{
+				// deleted files are created again
+				PreConfig: func() {
+					err := os.Remove(filename)
+					if err != nil {
+						t.Fatalf("Error deleting file: %s", err)
+					}
+				},
+				Config: testAccResourceUtilsYamlFile_config(filename, "b: 2"),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckYamlFile(filename, yamlFile_outputYaml, 0640),
+				),
+			},
+			{
+				Config: testAccResourceUtilsYamlFile_config(filename, "b: 3"),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("utils_yaml_file.test", "output", yamlFile_updatedOutputYaml),
+					testAccCheckYamlFile(filename, yamlFile_updatedOutputYaml, 0640),
+				),
+			},
+		},
+	})
+}
+
+const yamlFile_outputYaml = `a: 1
+b: 2
+`
+
+const yamlFile_updatedOutputYaml = `a: 1
+b: 3
+`
+
+func testAccCheckYamlFile(filename, content string, mode os.FileMode) resource.TestCheckFunc {
+	return func(s *terraform.State) error {
+		data, err := os.ReadFile(filename)
+		if err != nil {
+			return err
+		}
+		if string(data) != content {
+			return fmt.Errorf("unexpected content of %s: %q", filename, data)
+		}
+		info, err := os.Stat(filename)
+		if err != nil {
+			return err
+		}
+		if info.Mode().Perm() != mode {
+			return fmt.Errorf("unexpected permissions of %s: %04o", filename, info.Mode().Perm())
+		}
+		return nil
+	}
+}
+
+func testAccCheckYamlFileDestroyed(filename string) resource.TestCheckFunc {
+	return func(s *terraform.State) error {
+		if _, err := os.Stat(filename); !os.IsNotExist(err) {
+			return fmt.Errorf("file %s still exists", filename)
+		}
+		return nil
+	}
+}
+
+func testAccResourceUtilsYamlFile_config(filename, input string) string {
+	return fmt.Sprintf(`
+	resource "utils_yaml_file" "test" {
+	  input           = ["a: 1", %q]
+	  filename        = %q
+	  file_permission = "0640"
+	}
+	`, input, filename)
+}
+
+func TestWriteFile(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "a", "b", "file.yaml")
+
+	err := WriteFile(filename, []byte("a: 1\n"), "0600", "0750")
+	if err != nil {
+		t.Fatalf("Error writing file: %s", err)
+	}
+	info, err := os.Stat(filepath.Dir(filename))
+	if err != nil || info.Mode().Perm()&0700 != 0700 || info.Mode().Perm()&0007 != 0 {
+		t.Fatalf("Error matching directory permissions: %v: %v", info, err)
+	}
+
+	// existing files get the new permissions
+	err = WriteFile(filename, []byte("a: 2\n"), "0640", "0750")
+	if err != nil {
+		t.Fatalf("Error writing file: %s", err)
+	}
+	data, err := os.ReadFile(filename)
+	if err != nil || string(data) != "a: 2\n" {
+		t.Fatalf("Error matching content: %q: %v", data, err)
+	}
+	info, err = os.Stat(filename)
+	if err != nil || info.Mode().Perm() != 0640 {
+		t.Fatalf("Error matching file permissions: %v: %v", info, err)
+	}
+
+	err = WriteFile(filename, []byte("a: 1\n"), "rw", "0750")
+	if err == nil {
+		t.Fatalf("Expected error for invalid permissions")
+	}
+}
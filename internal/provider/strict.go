@@ -0,0 +1,42 @@
+package provider
+
+import (
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// yaml11Booleans are plain scalars, which are booleans in YAML 1.1, but
+// strings in YAML 1.2.
+var yaml11Booleans = map[string]bool{
+	"y": true, "Y": true, "yes": true, "Yes": true, "YES": true,
+	"n": true, "N": true, "no": true, "No": true, "NO": true,
+	"on": true, "On": true, "ON": true,
+	"off": true, "Off": true, "OFF": true,
+}
+
+// checkStrict reports unknown custom tags and duplicate keys if the Strict
+// option is set and YAML 1.1 booleans if the StrictBooleans option is set.
+func (i *CustomTagProcessor) checkStrict(node *yaml.Node) error {
+	if i.options.Strict && strings.HasPrefix(node.Tag, "!") && !strings.HasPrefix(node.Tag, "!!") {
+		return fmt.Errorf("line %d: unknown tag %s", node.Line, node.Tag)
+	}
+	if i.options.Strict && node.Kind == yaml.MappingNode {
+		keys := map[string]int{}
+		for j := 0; j+1 < len(node.Content); j += 2 {
+			key := node.Content[j]
+			if key.Kind != yaml.ScalarNode || key.ShortTag() == "!!merge" {
+				continue
+			}
+			if line, ok := keys[key.ShortTag()+":"+key.Value]; ok {
+				return fmt.Errorf("line %d: duplicate key '%s', first defined at line %d", key.Line, key.Value, line)
+			}
+			keys[key.ShortTag()+":"+key.Value] = key.Line
+		}
+	}
+	if i.options.StrictBooleans && node.Kind == yaml.ScalarNode && node.Style == 0 && node.Tag == "!!str" && yaml11Booleans[node.Value] {
+		return fmt.Errorf("line %d: '%s' is a boolean in YAML 1.1, use a quoted string or true/false instead", node.Line, node.Value)
+	}
+	return nil
+}
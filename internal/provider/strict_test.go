@@ -0,0 +1,78 @@
+package provider
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestYamlUnmarshalStrict(t *testing.T) {
+	cases := []struct {
+		input   string
+		options YamlOptions
+		err     string
+	}{
+		{
+			input:   "elem1: value\nelem2: !evn FOO\n",
+			options: YamlOptions{Strict: true},
+			err:     "line 2: unknown tag !evn",
+		},
+		{
+			input:   "elem1: value\nelem2:\n  child: !evn FOO\n",
+			options: YamlOptions{Strict: true},
+			err:     "line 3: unknown tag !evn",
+		},
+		{
+			input:   "elem1: value\nelem2: value\nelem1: value\n",
+			options: YamlOptions{Strict: true},
+			err:     "line 3: duplicate key 'elem1', first defined at line 1",
+		},
+		{
+			input:   "elem1:\n  - enabled: yes\n",
+			options: YamlOptions{StrictBooleans: true},
+			err:     "line 2: 'yes' is a boolean in YAML 1.1, use a quoted string or true/false instead",
+		},
+		{
+			input:   "on: value\n",
+			options: YamlOptions{StrictBooleans: true},
+			err:     "line 1: 'on' is a boolean in YAML 1.1",
+		},
+	}
+
+	for _, c := range cases {
+		var data map[string]interface{}
+		err := YamlUnmarshalWithOptions([]byte(c.input), &data, c.options)
+		if err == nil || !strings.Contains(err.Error(), c.err) {
+			t.Fatalf("Error matching error: %v vs %s", err, c.err)
+		}
+	}
+
+	valid := []struct {
+		input   string
+		options YamlOptions
+	}{
+		{
+			input:   "elem1: !!str 1\nelem2: !base64 value\nelem3: !join [',', a, b]\nelem4: !if {condition: 'true', value: 1}\n",
+			options: YamlOptions{Strict: true},
+		},
+		{
+			input:   "base: &base\n  elem1: value\nother:\n  <<: *base\n  elem2: value\n",
+			options: YamlOptions{Strict: true},
+		},
+		{
+			input:   "elem1: 'yes'\nelem2: \"on\"\nelem3: true\nelem4: yesterday\n",
+			options: YamlOptions{StrictBooleans: true},
+		},
+		{
+			input:   "elem1: !evn FOO\nelem2: yes\n",
+			options: YamlOptions{},
+		},
+	}
+
+	for _, c := range valid {
+		var data map[string]interface{}
+		err := YamlUnmarshalWithOptions([]byte(c.input), &data, c.options)
+		if err != nil {
+			t.Fatalf("Error reading YAML string: %s", err)
+		}
+	}
+}
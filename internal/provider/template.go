@@ -0,0 +1,124 @@
+package provider
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"text/template"
+	"text/template/parse"
+
+	"gopkg.in/yaml.v3"
+)
+
+// templateFuncs are the functions available in YAML templates in addition to
+// the text/template builtins. None of them access the filesystem or network.
+var templateFuncs = template.FuncMap{
+	"default": func(d, v interface{}) interface{} {
+		if v == nil || v == "" || v == false {
+			return d
+		}
+		return v
+	},
+	"indent": func(n int, s string) string {
+		pad := strings.Repeat(" ", n)
+		return pad + strings.ReplaceAll(s, "\n", "\n"+pad)
+	},
+	"join": func(sep string, v []interface{}) string {
+		s := make([]string, len(v))
+		for i, e := range v {
+			s[i] = fmt.Sprint(e)
+		}
+		return strings.Join(s, sep)
+	},
+	"lower":   strings.ToLower,
+	"quote":   strconv.Quote,
+	"replace": func(old, new, s string) string { return strings.ReplaceAll(s, old, new) },
+	"split":   func(sep, s string) []string { return strings.Split(s, sep) },
+	"toYaml": func(v interface{}) (string, error) {
+		b, err := yaml.Marshal(v)
+		return strings.TrimSuffix(string(b), "\n"), err
+	},
+	"trim":  strings.TrimSpace,
+	"upper": strings.ToUpper,
+}
+
+// templateLineMarker marks the beginning of a line in the rendered output
+// with its line number in the template. YAML documents cannot contain NUL
+// characters, so the markers never collide with template text. Rendered
+// values containing NUL characters are rejected.
+const templateLineMarker = "\x00"
+
+// renderTemplate renders a YAML string as Go template and returns the
+// rendered YAML string together with the template line number of each
+// rendered line.
+func renderTemplate(name string, in []byte, vars interface{}) ([]byte, []int, error) {
+	t, err := template.New(name).Funcs(templateFuncs).Option("missingkey=error").Parse(string(in))
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, tt := range t.Templates() {
+		if tt.Tree != nil {
+			addLineMarkers(tt.Tree.Root, string(in))
+		}
+	}
+	var b bytes.Buffer
+	err = t.Execute(&b, vars)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	rendered := strings.Split(b.String(), "\n")
+	lines := make([]int, len(rendered))
+	line := 1
+	for i, l := range rendered {
+		if strings.HasPrefix(l, templateLineMarker) {
+			end := strings.Index(l[1:], templateLineMarker) + 1
+			if end > 0 {
+				if n, err := strconv.Atoi(l[1:end]); err == nil {
+					line = n
+					l = l[end+1:]
+				}
+			}
+		}
+		if strings.Contains(l, templateLineMarker) {
+			return nil, nil, fmt.Errorf("line %d: rendered template contains NUL characters", line)
+		}
+		rendered[i] = l
+		lines[i] = line
+	}
+	return []byte(strings.Join(rendered, "\n")), lines, nil
+}
+
+// addLineMarkers adds a line marker after each newline of the template text.
+func addLineMarkers(node parse.Node, src string) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			addLineMarkers(child, src)
+		}
+	case *parse.IfNode:
+		addLineMarkers(n.List, src)
+		addLineMarkers(n.ElseList, src)
+	case *parse.RangeNode:
+		addLineMarkers(n.List, src)
+		addLineMarkers(n.ElseList, src)
+	case *parse.WithNode:
+		addLineMarkers(n.List, src)
+		addLineMarkers(n.ElseList, src)
+	case *parse.TextNode:
+		line := 1 + strings.Count(src[:n.Pos], "\n")
+		var b bytes.Buffer
+		for _, c := range n.Text {
+			b.WriteByte(c)
+			if c == '\n' {
+				line++
+				b.WriteString(templateLineMarker + strconv.Itoa(line) + templateLineMarker)
+			}
+		}
+		n.Text = b.Bytes()
+	}
+}
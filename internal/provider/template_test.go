@@ -0,0 +1,116 @@
+package provider
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestYamlUnmarshalTemplate(t *testing.T) {
+	vars := map[string]interface{}{
+		"tenant": "tenant1",
+		"vrfs":   []interface{}{"vrf1", "vrf2"},
+		"bd":     map[string]interface{}{"name": "bd1"},
+	}
+
+	cases := []struct {
+		input  string
+		result map[string]interface{}
+	}{
+		{
+			input: `
+tenant: {{ .tenant | upper }}
+vrfs:
+{{- range .vrfs }}
+  - name: {{ . }}
+{{- end }}
+description: {{ default "none" .missing_ok }}
+`,
+			result: map[string]interface{}{
+				"tenant":      "TENANT1",
+				"vrfs":        []interface{}{map[string]interface{}{"name": "vrf1"}, map[string]interface{}{"name": "vrf2"}},
+				"description": "none",
+			},
+		},
+		{
+			input: `
+bd:
+{{ toYaml .bd | indent 2 }}
+vrfs: [{{ join ", " .vrfs }}]
+`,
+			result: map[string]interface{}{
+				"bd":   map[string]interface{}{"name": "bd1"},
+				"vrfs": []interface{}{"vrf1", "vrf2"},
+			},
+		},
+	}
+
+	vars["missing_ok"] = ""
+	for _, c := range cases {
+		var data map[string]interface{}
+		err := YamlUnmarshalWithOptions([]byte(c.input), &data, YamlOptions{TemplateVars: vars})
+		if err != nil {
+			t.Fatalf("Error reading YAML string: %s", err)
+		}
+		if !reflect.DeepEqual(data, c.result) {
+			t.Fatalf("Error matching data and result: %#v vs %#v", data, c.result)
+		}
+	}
+}
+
+func TestYamlUnmarshalTemplateErrors(t *testing.T) {
+	vars := map[string]interface{}{
+		"vrfs": []interface{}{"vrf1", "vrf2", "vrf3", "vrf4", "vrf5"},
+		"nul":  "\x00oops",
+	}
+
+	cases := []struct {
+		input string
+		err   string
+	}{
+		// parse errors
+		{
+			input: "elem1: value\nelem2: {{ .vrfs | unknown }}\n",
+			err:   `template: input[2]:2: function "unknown" not defined`,
+		},
+		// execution errors
+		{
+			input: "elem1: value\n\nelem2: {{ .missing }}\n",
+			err:   `template: input[2]:3:10: executing "input[2]" at <.missing>: map has no entry for key "missing"`,
+		},
+		// YAML errors report the line of the template
+		{
+			input: "vrfs:\n{{- range .vrfs }}\n  - {{ . }}\n{{- end }}\nelem1: value\n  elem2: value\n",
+			err:   "line 6",
+		},
+		// NUL characters of values are not taken for line markers
+		{
+			input: "{{ .nul }}\n",
+			err:   "line 1: rendered template contains NUL characters",
+		},
+		{
+			input: "elem1: value\n{{ .nul }}\n",
+			err:   "line 2: rendered template contains NUL characters",
+		},
+		{
+			input: "elem1: value\nelem2: {{ .nul }}\n",
+			err:   "line 2: rendered template contains NUL characters",
+		},
+		// line numbers of deferred interpolations
+		{
+			input: "vrfs:\n{{- range .vrfs }}\n  - {{ . }}\n{{- end }}\nelem1: ${ref:missing}\n",
+			err:   "input[2] line 5: !ref missing",
+		},
+	}
+
+	for _, c := range cases {
+		var data map[string]interface{}
+		err := YamlUnmarshalWithOptions([]byte(c.input), &data, YamlOptions{TemplateVars: vars, Interpolate: true, Name: "input[2]"})
+		if err == nil {
+			_, err = ResolveRefs(data)
+		}
+		if err == nil || !strings.Contains(err.Error(), c.err) {
+			t.Fatalf("Error matching error: %v vs %s", err, c.err)
+		}
+	}
+}
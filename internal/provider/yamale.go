@@ -0,0 +1,608 @@
+package provider
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"gopkg.in/yaml.v3"
+)
+
+// YamaleSchema is a parsed schema in the compact Yamale format, where every
+// leaf of the schema document is a validator expression like `str()` or
+// `int(min=1, max=4094)`. Additional YAML documents in the schema define
+// named schemas which can be referenced with `include('name')`.
+type YamaleSchema struct {
+	root     *yamaleValidator
+	includes map[string]*yamaleValidator
+}
+
+type yamaleValidator struct {
+	name   string
+	args   []interface{}
+	kwargs map[string]interface{}
+	// keys and fields are set for mappings defined in the schema document
+	keys   []string
+	fields map[string]*yamaleValidator
+}
+
+func ParseYamaleSchema(in []byte) (*YamaleSchema, error) {
+	schema := &YamaleSchema{includes: map[string]*yamaleValidator{}}
+	decoder := yaml.NewDecoder(bytes.NewReader(in))
+	for i := 0; ; i++ {
+		var node yaml.Node
+		err := decoder.Decode(&node)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, err
+		}
+		if len(node.Content) == 0 {
+			continue
+		}
+		if i == 0 {
+			schema.root, err = parseYamaleNode(node.Content[0])
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+		// every further document defines includes
+		if node.Content[0].Kind != yaml.MappingNode {
+			return nil, fmt.Errorf("line %d: includes must be defined as a map", node.Content[0].Line)
+		}
+		for j := 0; j < len(node.Content[0].Content); j += 2 {
+			v, err := parseYamaleNode(node.Content[0].Content[j+1])
+			if err != nil {
+				return nil, err
+			}
+			schema.includes[node.Content[0].Content[j].Value] = v
+		}
+	}
+	if schema.root == nil {
+		return nil, fmt.Errorf("schema is empty")
+	}
+	return schema, nil
+}
+
+func parseYamaleNode(node *yaml.Node) (*yamaleValidator, error) {
+	switch node.Kind {
+	case yaml.AliasNode:
+		return parseYamaleNode(node.Alias)
+	case yaml.MappingNode:
+		v := &yamaleValidator{name: "map", fields: map[string]*yamaleValidator{}}
+		for i := 0; i < len(node.Content); i += 2 {
+			field, err := parseYamaleNode(node.Content[i+1])
+			if err != nil {
+				return nil, err
+			}
+			key := node.Content[i].Value
+			v.keys = append(v.keys, key)
+			v.fields[key] = field
+		}
+		return v, nil
+	case yaml.SequenceNode:
+		v := &yamaleValidator{name: "list"}
+		for _, item := range node.Content {
+			arg, err := parseYamaleNode(item)
+			if err != nil {
+				return nil, err
+			}
+			v.args = append(v.args, arg)
+		}
+		return v, nil
+	case yaml.ScalarNode:
+		p := &yamaleParser{input: node.Value}
+		v, err := p.parse()
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid schema expression '%s': %s", node.Line, node.Value, err)
+		}
+		return v, nil
+	}
+	return nil, fmt.Errorf("line %d: invalid schema node", node.Line)
+}
+
+// yamaleParser parses a single validator expression, e.g. `list(include('vrf'), min=1)`.
+type yamaleParser struct {
+	input string
+	pos   int
+}
+
+func (p *yamaleParser) parse() (*yamaleValidator, error) {
+	v, err := p.parseCall()
+	if err != nil {
+		return nil, err
+	}
+	p.skipSpace()
+	if p.pos < len(p.input) {
+		return nil, fmt.Errorf("unexpected '%s'", p.input[p.pos:])
+	}
+	return v, nil
+}
+
+func (p *yamaleParser) skipSpace() {
+	for p.pos < len(p.input) && unicode.IsSpace(rune(p.input[p.pos])) {
+		p.pos++
+	}
+}
+
+func (p *yamaleParser) parseIdent() string {
+	p.skipSpace()
+	start := p.pos
+	for p.pos < len(p.input) && (p.input[p.pos] == '_' || unicode.IsLetter(rune(p.input[p.pos])) || unicode.IsDigit(rune(p.input[p.pos]))) {
+		p.pos++
+	}
+	return p.input[start:p.pos]
+}
+
+func (p *yamaleParser) expect(c byte) error {
+	p.skipSpace()
+	if p.pos >= len(p.input) || p.input[p.pos] != c {
+		return fmt.Errorf("expected '%c' at position %d", c, p.pos)
+	}
+	p.pos++
+	return nil
+}
+
+func (p *yamaleParser) parseCall() (*yamaleValidator, error) {
+	name := p.parseIdent()
+	if name == "" {
+		return nil, fmt.Errorf("expected validator name at position %d", p.pos)
+	}
+	if _, ok := yamaleTypeNames[name]; !ok {
+		return nil, fmt.Errorf("unknown validator '%s'", name)
+	}
+	v := &yamaleValidator{name: name, kwargs: map[string]interface{}{}}
+	if err := p.expect('('); err != nil {
+		return nil, err
+	}
+	p.skipSpace()
+	if p.pos < len(p.input) && p.input[p.pos] == ')' {
+		p.pos++
+		return v, nil
+	}
+	for {
+		// keyword argument
+		start := p.pos
+		ident := p.parseIdent()
+		p.skipSpace()
+		if ident != "" && p.pos < len(p.input) && p.input[p.pos] == '=' {
+			p.pos++
+			value, err := p.parseValue()
+			if err != nil {
+				return nil, err
+			}
+			v.kwargs[ident] = value
+		} else {
+			p.pos = start
+			value, err := p.parseValue()
+			if err != nil {
+				return nil, err
+			}
+			v.args = append(v.args, value)
+		}
+		p.skipSpace()
+		if p.pos < len(p.input) && p.input[p.pos] == ',' {
+			p.pos++
+			continue
+		}
+		if err := p.expect(')'); err != nil {
+			return nil, err
+		}
+		return v, nil
+	}
+}
+
+func (p *yamaleParser) parseValue() (interface{}, error) {
+	p.skipSpace()
+	if p.pos >= len(p.input) {
+		return nil, fmt.Errorf("unexpected end of expression")
+	}
+	c := p.input[p.pos]
+	switch {
+	case c == '\'' || c == '"':
+		end := strings.IndexByte(p.input[p.pos+1:], c)
+		if end < 0 {
+			return nil, fmt.Errorf("unterminated string at position %d", p.pos)
+		}
+		s := p.input[p.pos+1 : p.pos+1+end]
+		p.pos += end + 2
+		return s, nil
+	case c == '-' || c == '.' || unicode.IsDigit(rune(c)):
+		start := p.pos
+		p.pos++
+		for p.pos < len(p.input) && (p.input[p.pos] == '.' || p.input[p.pos] == 'e' || unicode.IsDigit(rune(p.input[p.pos]))) {
+			p.pos++
+		}
+		literal := p.input[start:p.pos]
+		if i, err := strconv.Atoi(literal); err == nil {
+			return i, nil
+		}
+		f, err := strconv.ParseFloat(literal, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number '%s'", literal)
+		}
+		return f, nil
+	}
+	start := p.pos
+	ident := p.parseIdent()
+	switch ident {
+	case "True", "true":
+		return true, nil
+	case "False", "false":
+		return false, nil
+	case "None", "null":
+		return nil, nil
+	}
+	p.pos = start
+	return p.parseCall()
+}
+
+// yamaleTypeNames maps validator names to the type name used in error messages.
+var yamaleTypeNames = map[string]string{
+	"any":       "any",
+	"bool":      "bool",
+	"day":       "day",
+	"enum":      "enum",
+	"include":   "include",
+	"int":       "int",
+	"ip":        "ip",
+	"list":      "list",
+	"mac":       "mac",
+	"map":       "map",
+	"null":      "null",
+	"num":       "num",
+	"regex":     "regex match",
+	"semver":    "semver",
+	"str":       "str",
+	"subset":    "subset",
+	"timestamp": "timestamp",
+}
+
+// yamaleRegex compiles a pattern which is anchored at the start of the
+// string like Python's `re.match` used by Yamale.
+func yamaleRegex(flags, pattern string) (*regexp.Regexp, error) {
+	return regexp.Compile(flags + `\A(?:` + pattern + ")")
+}
+
+var yamaleSemverRegex = regexp.MustCompile(`^(0|[1-9]\d*)\.(0|[1-9]\d*)\.(0|[1-9]\d*)(?:-((?:0|[1-9]\d*|\d*[a-zA-Z-][0-9a-zA-Z-]*)(?:\.(?:0|[1-9]\d*|\d*[a-zA-Z-][0-9a-zA-Z-]*))*))?(?:\+([0-9a-zA-Z-]+(?:\.[0-9a-zA-Z-]+)*))?$`)
+
+// Validate validates data against the schema and returns a list of errors in
+// the form `path: message`. If partial is true, data is treated as a fragment
+// of a larger document and missing required fields or too short lists and
+// maps are not reported.
+func (s *YamaleSchema) Validate(data interface{}, partial bool) []string {
+	return s.validate(s.root, data, "", partial, true)
+}
+
+func (s *YamaleSchema) validate(v *yamaleValidator, data interface{}, path string, partial, strict bool) []string {
+	if data == nil {
+		if v.optional() && v.kwargBool("none", true) || v.name == "null" || v.name == "any" && len(v.args) == 0 {
+			return nil
+		}
+		return []string{yamaleError(path, "Required field missing")}
+	}
+	if v.fields != nil {
+		return s.validateFields(v, data, path, partial, strict)
+	}
+	value := reflect.ValueOf(data)
+	switch v.name {
+	case "any":
+		if len(v.args) == 0 {
+			return nil
+		}
+		return s.validateAny(v.args, data, path, partial, strict)
+	case "include":
+		name, _ := v.arg(0).(string)
+		include, ok := s.includes[name]
+		if !ok {
+			return []string{yamaleError(path, fmt.Sprintf("Include '%s' has not been defined", name))}
+		}
+		return s.validate(include, data, path, partial, v.kwargBool("strict", true))
+	case "list", "subset":
+		if value.Kind() != reflect.Slice {
+			return []string{yamaleTypeError(path, data, v.name)}
+		}
+		if v.name == "subset" && value.Len() == 0 && !v.kwargBool("allow_empty", false) {
+			return []string{yamaleError(path, "subset may not be an empty set.")}
+		}
+		errs := yamaleLength(v, path, data, value.Len(), partial)
+		for i := 0; i < value.Len(); i++ {
+			if len(v.args) > 0 {
+				errs = append(errs, s.validateAny(v.args, value.Index(i).Interface(), yamalePath(path, i), partial, strict)...)
+			}
+		}
+		return errs
+	case "map":
+		if value.Kind() != reflect.Map {
+			return []string{yamaleTypeError(path, data, v.name)}
+		}
+		errs := yamaleLength(v, path, data, value.Len(), partial)
+		keyValidator, _ := v.kwargs["key"].(*yamaleValidator)
+		for _, key := range sortedMapKeys(value) {
+			item := value.MapIndex(key).Interface()
+			if keyValidator != nil {
+				errs = append(errs, s.validate(keyValidator, key.Interface(), yamalePath(path, key.Interface()), partial, strict)...)
+			}
+			if len(v.args) > 0 {
+				errs = append(errs, s.validateAny(v.args, item, yamalePath(path, key.Interface()), partial, strict)...)
+			}
+		}
+		return errs
+	}
+	return yamaleScalar(v, data, path)
+}
+
+func (s *YamaleSchema) validateFields(v *yamaleValidator, data interface{}, path string, partial, strict bool) []string {
+	value := reflect.ValueOf(data)
+	if value.Kind() != reflect.Map {
+		return []string{yamaleTypeError(path, data, "map")}
+	}
+	var errs []string
+	seen := map[string]bool{}
+	for _, key := range v.keys {
+		seen[key] = true
+		item := value.MapIndex(reflect.ValueOf(key))
+		if !item.IsValid() {
+			if !partial && !v.fields[key].optional() {
+				errs = append(errs, yamaleError(yamalePath(path, key), "Required field missing"))
+			}
+			continue
+		}
+		errs = append(errs, s.validate(v.fields[key], item.Interface(), yamalePath(path, key), partial, strict)...)
+	}
+	if strict {
+		for _, key := range sortedMapKeys(value) {
+			if !seen[fmt.Sprint(key.Interface())] {
+				errs = append(errs, yamaleError(yamalePath(path, key.Interface()), "Unexpected element"))
+			}
+		}
+	}
+	return errs
+}
+
+// validateAny succeeds if data matches at least one of the validators,
+// otherwise the errors of all validators are returned.
+func (s *YamaleSchema) validateAny(args []interface{}, data interface{}, path string, partial, strict bool) []string {
+	var errs []string
+	for _, arg := range args {
+		v, ok := arg.(*yamaleValidator)
+		if !ok {
+			continue
+		}
+		e := s.validate(v, data, path, partial, strict)
+		if len(e) == 0 {
+			return nil
+		}
+		errs = append(errs, e...)
+	}
+	return errs
+}
+
+func yamaleScalar(v *yamaleValidator, data interface{}, path string) []string {
+	value := reflect.ValueOf(data)
+	switch v.name {
+	case "str":
+		s, ok := data.(string)
+		if !ok {
+			return []string{yamaleTypeError(path, data, v.name)}
+		}
+		errs := yamaleLength(v, path, data, len([]rune(s)), false)
+		if exclude, ok := v.kwargs["exclude"].(string); ok {
+			for _, c := range exclude {
+				if strings.ContainsRune(s, c) {
+					errs = append(errs, yamaleError(path, fmt.Sprintf("'%s' contains excluded character '%c'", s, c)))
+				}
+			}
+		}
+		if equals, ok := v.kwargs["equals"].(string); ok && s != equals {
+			errs = append(errs, yamaleError(path, fmt.Sprintf("'%s' does not equal '%s'", s, equals)))
+		}
+		if prefix, ok := v.kwargs["starts_with"].(string); ok && !strings.HasPrefix(s, prefix) {
+			errs = append(errs, yamaleError(path, fmt.Sprintf("'%s' does not start with '%s'", s, prefix)))
+		}
+		if suffix, ok := v.kwargs["ends_with"].(string); ok && !strings.HasSuffix(s, suffix) {
+			errs = append(errs, yamaleError(path, fmt.Sprintf("'%s' does not end with '%s'", s, suffix)))
+		}
+		if pattern, ok := v.kwargs["matches"].(string); ok {
+			if re, err := yamaleRegex("", pattern); err != nil || !re.MatchString(s) {
+				errs = append(errs, yamaleError(path, fmt.Sprintf("'%s' does not match '%s'", s, pattern)))
+			}
+		}
+		return errs
+	case "int", "num":
+		n, ok := yamaleNumber(value, v.name == "int")
+		if !ok {
+			return []string{yamaleTypeError(path, data, v.name)}
+		}
+		var errs []string
+		if min, ok := yamaleFloat(v.kwargs["min"]); ok && n < min {
+			errs = append(errs, yamaleError(path, fmt.Sprintf("%v is less than %v", data, v.kwargs["min"])))
+		}
+		if max, ok := yamaleFloat(v.kwargs["max"]); ok && n > max {
+			errs = append(errs, yamaleError(path, fmt.Sprintf("%v is greater than %v", data, v.kwargs["max"])))
+		}
+		return errs
+	case "bool":
+		if _, ok := data.(bool); !ok {
+			return []string{yamaleTypeError(path, data, v.name)}
+		}
+	case "null":
+		return []string{yamaleTypeError(path, data, v.name)}
+	case "enum":
+		for _, arg := range v.args {
+			if yamaleEqual(arg, data) {
+				return nil
+			}
+		}
+		options := make([]string, len(v.args))
+		for i, arg := range v.args {
+			options[i] = yamaleRepr(arg)
+		}
+		return []string{yamaleError(path, fmt.Sprintf("%s not in (%s)", yamaleRepr(data), strings.Join(options, ", ")))}
+	case "day", "timestamp":
+		if _, ok := data.(time.Time); ok {
+			return nil
+		}
+		s, ok := data.(string)
+		layouts := []string{"2006-01-02"}
+		if v.name == "timestamp" {
+			layouts = []string{time.RFC3339Nano, "2006-01-02T15:04:05", "2006-01-02 15:04:05", "2006-01-02 15:04:05Z07:00"}
+		}
+		if ok {
+			for _, layout := range layouts {
+				if _, err := time.Parse(layout, s); err == nil {
+					return nil
+				}
+			}
+		}
+		return []string{yamaleTypeError(path, data, v.name)}
+	case "regex":
+		s, ok := data.(string)
+		flags := ""
+		if v.kwargBool("ignore_case", false) {
+			flags += "i"
+		}
+		if v.kwargBool("multiline", false) {
+			flags += "m"
+		}
+		if v.kwargBool("dotall", false) {
+			flags += "s"
+		}
+		if flags != "" {
+			flags = "(?" + flags + ")"
+		}
+		for _, arg := range v.args {
+			pattern, _ := arg.(string)
+			if re, err := yamaleRegex(flags, pattern); ok && err == nil && re.MatchString(s) {
+				return nil
+			}
+		}
+		name := yamaleTypeNames[v.name]
+		if n, ok := v.kwargs["name"].(string); ok {
+			name = n
+		}
+		return []string{yamaleTypeError(path, data, name)}
+	case "ip":
+		s, _ := data.(string)
+		ip := net.ParseIP(s)
+		if ip == nil {
+			if parsed, _, err := net.ParseCIDR(s); err == nil {
+				ip = parsed
+			}
+		}
+		version, _ := v.kwargs["version"].(int)
+		if ip == nil || version == 4 && ip.To4() == nil || version == 6 && ip.To4() != nil {
+			return []string{yamaleTypeError(path, data, v.name)}
+		}
+	case "mac":
+		s, _ := data.(string)
+		if _, err := net.ParseMAC(s); err != nil {
+			return []string{yamaleTypeError(path, data, v.name)}
+		}
+	case "semver":
+		s, _ := data.(string)
+		if !yamaleSemverRegex.MatchString(s) {
+			return []string{yamaleTypeError(path, data, v.name)}
+		}
+	}
+	return nil
+}
+
+func yamaleLength(v *yamaleValidator, path string, data interface{}, length int, partial bool) []string {
+	var errs []string
+	if min, ok := yamaleFloat(v.kwargs["min"]); ok && float64(length) < min && !partial {
+		errs = append(errs, yamaleError(path, fmt.Sprintf("Length of %s is less than %v", yamaleRepr(data), v.kwargs["min"])))
+	}
+	if max, ok := yamaleFloat(v.kwargs["max"]); ok && float64(length) > max {
+		errs = append(errs, yamaleError(path, fmt.Sprintf("Length of %s is greater than %v", yamaleRepr(data), v.kwargs["max"])))
+	}
+	return errs
+}
+
+func (v *yamaleValidator) optional() bool {
+	return !v.kwargBool("required", true)
+}
+
+func (v *yamaleValidator) kwargBool(name string, def bool) bool {
+	if b, ok := v.kwargs[name].(bool); ok {
+		return b
+	}
+	return def
+}
+
+func (v *yamaleValidator) arg(i int) interface{} {
+	if i < len(v.args) {
+		return v.args[i]
+	}
+	return nil
+}
+
+func yamaleNumber(value reflect.Value, integer bool) (float64, bool) {
+	switch value.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(value.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(value.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		if integer {
+			return 0, false
+		}
+		return value.Float(), true
+	}
+	return 0, false
+}
+
+func yamaleFloat(v interface{}) (float64, bool) {
+	if v == nil {
+		return 0, false
+	}
+	return yamaleNumber(reflect.ValueOf(v), false)
+}
+
+func yamaleEqual(a, b interface{}) bool {
+	fa, okA := yamaleFloat(a)
+	fb, okB := yamaleFloat(b)
+	if okA && okB {
+		return fa == fb || math.IsNaN(fa) && math.IsNaN(fb)
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+func yamaleRepr(v interface{}) string {
+	if s, ok := v.(string); ok {
+		return "'" + s + "'"
+	}
+	return fmt.Sprint(v)
+}
+
+func yamaleTypeError(path string, data interface{}, name string) string {
+	if t, ok := yamaleTypeNames[name]; ok {
+		name = t
+	}
+	return yamaleError(path, fmt.Sprintf("'%v' is not a %s.", data, name))
+}
+
+func yamaleError(path, msg string) string {
+	if path == "" {
+		return msg
+	}
+	return path + ": " + msg
+}
+
+func yamalePath(path string, key interface{}) string {
+	if path == "" {
+		return fmt.Sprint(key)
+	}
+	return path + "." + fmt.Sprint(key)
+}
@@ -0,0 +1,172 @@
+package provider
+
+import (
+	"reflect"
+	"testing"
+)
+
+const yamaleTestSchema = `
+tenants: list(include('tenant'), min=1)
+version: semver(required=False)
+---
+tenant:
+  name: str(max=10, exclude=' ')
+  vlan: int(min=1, max=4094, required=False)
+  mode: enum('prod', 'dev', required=False)
+  vrfs: list(include('vrf'), required=False)
+vrf:
+  name: regex('^[a-z]+$', name='lowercase name')
+  enforced: bool(required=False)
+`
+
+func TestYamaleValidate(t *testing.T) {
+	schema, err := ParseYamaleSchema([]byte(yamaleTestSchema))
+	if err != nil {
+		t.Fatalf("Error parsing schema: %s", err)
+	}
+
+	cases := []struct {
+		input   string
+		partial bool
+		errors  []string
+	}{
+		// valid document
+		{
+			input: `
+tenants:
+  - name: prod
+    vlan: 10
+    mode: prod
+    vrfs:
+      - name: vrf
+        enforced: true
+version: 1.2.3
+`,
+			errors: nil,
+		},
+		// type and constraint errors
+		{
+			input: `
+tenants:
+  - name: a very long name
+    vlan: 5000
+    mode: test
+    vrfs:
+      - name: VRF1
+        enforced: yes please
+`,
+			errors: []string{
+				"tenants.0.name: Length of 'a very long name' is greater than 10",
+				"tenants.0.name: 'a very long name' contains excluded character ' '",
+				"tenants.0.vlan: 5000 is greater than 4094",
+				"tenants.0.mode: 'test' not in ('prod', 'dev')",
+				"tenants.0.vrfs.0.name: 'VRF1' is not a lowercase name.",
+				"tenants.0.vrfs.0.enforced: 'yes please' is not a bool.",
+			},
+		},
+		// missing and unexpected elements
+		{
+			input: `
+tenants:
+  - vlan: 1
+    description: abc
+other: 1
+`,
+			errors: []string{
+				"tenants.0.name: Required field missing",
+				"tenants.0.description: Unexpected element",
+				"other: Unexpected element",
+			},
+		},
+		// missing fields are ignored for partial documents
+		{
+			input: `
+tenants:
+  - vlan: 1
+`,
+			partial: true,
+			errors:  nil,
+		},
+		// list length
+		{
+			input: `
+tenants: []
+`,
+			errors: []string{
+				"tenants: Length of [] is less than 1",
+			},
+		},
+	}
+
+	for _, c := range cases {
+		var data map[interface{}]interface{}
+		err := YamlUnmarshal([]byte(c.input), &data)
+		if err != nil {
+			t.Fatalf("Error reading YAML string: %s", err)
+		}
+		errors := schema.Validate(data, c.partial)
+		if !reflect.DeepEqual(errors, c.errors) {
+			t.Fatalf("Error matching validation errors: %#v vs %#v", errors, c.errors)
+		}
+	}
+}
+
+func TestYamaleRegex(t *testing.T) {
+	schema, err := ParseYamaleSchema([]byte(`
+regex: regex('abc', multiline=True, required=False)
+str: str(matches='b+', required=False)
+`))
+	if err != nil {
+		t.Fatalf("Error parsing schema: %s", err)
+	}
+
+	// patterns are anchored at the start like Python's re.match
+	cases := []struct {
+		input  string
+		errors []string
+	}{
+		{
+			input:  "regex: abcd\nstr: bbc",
+			errors: nil,
+		},
+		{
+			input:  "regex: xabc",
+			errors: []string{"regex: 'xabc' is not a regex match."},
+		},
+		{
+			input:  "regex: \"x\\nabc\"",
+			errors: []string{"regex: 'x\nabc' is not a regex match."},
+		},
+		{
+			input:  "str: abb",
+			errors: []string{"str: 'abb' does not match 'b+'"},
+		},
+	}
+
+	for _, c := range cases {
+		var data map[interface{}]interface{}
+		err := YamlUnmarshal([]byte(c.input), &data)
+		if err != nil {
+			t.Fatalf("Error reading YAML string: %s", err)
+		}
+		errors := schema.Validate(data, false)
+		if !reflect.DeepEqual(errors, c.errors) {
+			t.Fatalf("Error matching validation errors: %#v vs %#v", errors, c.errors)
+		}
+	}
+}
+
+func TestParseYamaleSchema(t *testing.T) {
+	cases := []string{
+		`name: str(`,
+		`name: unknown()`,
+		`name: str(min=1 max=2)`,
+		``,
+	}
+
+	for _, c := range cases {
+		if _, err := ParseYamaleSchema([]byte(c)); err == nil {
+			t.Fatalf("Expected error parsing schema: %s", c)
+		}
+	}
+}
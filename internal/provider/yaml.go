@@ -1,9 +1,14 @@
 package provider
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"errors"
 	"fmt"
-	"os"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
 	"sync"
 
 	"gopkg.in/yaml.v3"
@@ -12,43 +17,258 @@ import (
 var tagResolvers = make(map[string]func(*yaml.Node) (*yaml.Node, error))
 var tagResolversMutex = &sync.Mutex{}
 
+// YamlOptions controls how YAML strings are decoded by YamlUnmarshalWithOptions.
+type YamlOptions struct {
+	// SharedAnchors enables references to anchors defined in previously
+	// decoded YAML strings. Anchors of the decoded YAML string are added to the
+	// map, so the same map must be passed for all YAML strings.
+	SharedAnchors map[string]*yaml.Node
+	// Interpolate enables `${env.NAME}` and `${ref:path}` expressions in
+	// string values.
+	Interpolate bool
+	// Name identifies the YAML string in error messages, e.g. `input[0]`.
+	Name string
+	// TemplateVars enables rendering of the YAML string as Go template with
+	// the given data before decoding it.
+	TemplateVars interface{}
+	// Refs is the document referenced by `ref:` operands in conditions of
+	// `!if` and `!unless` tags.
+	Refs interface{}
+	// BaseDir is the directory of files referenced by `!file`, `!base64file`
+	// and `!filehash` tags. Defaults to the current working directory.
+	BaseDir string
+	// Strict enables errors for unknown custom tags and duplicate keys.
+	Strict bool
+	// StrictBooleans enables errors for YAML 1.1 booleans like `yes` or `on`,
+	// which are strings in YAML 1.2.
+	StrictBooleans bool
+	// ScalarStyles records the original representation of scalars, which
+	// can be restored by YamlMarshal.
+	ScalarStyles *ScalarStyles
+	// Env are variables of env files resolved by `!env` tags, which take
+	// precedence over the env files of the provider and the process
+	// environment.
+	Env map[string]string
+	// Variables are resolved by `!var` tags.
+	Variables map[string]string
+	// EnvFromVariables resolves environment variables from Variables instead
+	// of env files and the process environment.
+	EnvFromVariables bool
+	// CustomTags are tags defined in the provider configuration.
+	CustomTags []CustomTag
+}
+
 type CustomTagProcessor struct {
-	target interface{}
+	target  interface{}
+	options YamlOptions
+	// resolvers are tag resolvers depending on options, which take
+	// precedence over resolvers added by AddResolvers
+	resolvers map[string]func(*yaml.Node) (*yaml.Node, error)
+	// resolved maps nodes to their resolved nodes, so that nodes referenced
+	// by multiple aliases are only resolved once
+	resolved map[*yaml.Node]*yaml.Node
+	// sharedAnchors is set if anchors of previous YAML strings have been
+	// added to the document
+	sharedAnchors bool
+	// offset is the number of lines added to the document
+	offset int
+	// lines maps lines of a rendered template to lines of the template
+	lines []int
+	// markers maps placeholders of scalar values to the values replacing
+	// them after decoding, e.g. refValue for `!ref` tags
+	markers map[string]interface{}
+	// nonce makes placeholders unique, so they never match regular strings
+	nonce string
 }
 
 func (i *CustomTagProcessor) UnmarshalYAML(value *yaml.Node) error {
+	if i.resolved == nil {
+		i.resolved = make(map[*yaml.Node]*yaml.Node)
+	}
+	if i.sharedAnchors && value.Kind == yaml.MappingNode && len(value.Content) >= 2 && value.Content[0].Value == sharedAnchorsKey {
+		// shared anchors have already been resolved in previous documents
+		markResolved(value.Content[1], i.resolved)
+		value.Content = value.Content[2:]
+	}
 	tagResolversMutex.Lock()
-	resolved, err := resolveTags(value)
+	resolved, err := i.resolveTags(value)
 	tagResolversMutex.Unlock()
 	if err != nil {
 		return err
 	}
-	return resolved.Decode(i.target)
+	if i.options.SharedAnchors != nil {
+		collectAnchors(resolved, i.options.SharedAnchors)
+	}
+	if i.options.ScalarStyles != nil {
+		i.options.ScalarStyles.collect(resolved, "")
+	}
+	err = resolved.Decode(i.target)
+	if err != nil || len(i.markers) == 0 {
+		return err
+	}
+	target := reflect.ValueOf(i.target).Elem()
+	decoded, err := replaceMarkers(target.Interface(), i.markers)
+	if err != nil {
+		return err
+	}
+	target.Set(reflectValue(decoded, target.Type()))
+	return nil
+}
+
+// mark replaces the value of a scalar node with a placeholder, which is
+// replaced with v after decoding, so that v is never confused with a string.
+func (i *CustomTagProcessor) mark(node *yaml.Node, v interface{}) {
+	if i.markers == nil {
+		i.markers = map[string]interface{}{}
+		nonce := make([]byte, 16)
+		_, _ = rand.Read(nonce)
+		i.nonce = hex.EncodeToString(nonce)
+	}
+	placeholder := fmt.Sprintf("\x00%s:%d", i.nonce, len(i.markers))
+	i.markers[placeholder] = v
+	node.Value = placeholder
+}
+
+// marked returns the value of a node replaced by a placeholder.
+func (i *CustomTagProcessor) marked(node *yaml.Node) (interface{}, bool) {
+	if node.Kind == yaml.AliasNode {
+		return i.marked(node.Alias)
+	}
+	if node.Kind != yaml.ScalarNode {
+		return nil, false
+	}
+	v, ok := i.markers[node.Value]
+	return v, ok
+}
+
+// replaceMarkers replaces all placeholders in a decoded value.
+func replaceMarkers(v interface{}, markers map[string]interface{}) (interface{}, error) {
+	if s, ok := v.(string); ok {
+		if marker, ok := markers[s]; ok {
+			return marker, nil
+		}
+		return v, nil
+	}
+	set := func(element reflect.Value, t reflect.Type, fn func(reflect.Value)) error {
+		s, ok := element.Interface().(string)
+		if !ok {
+			// maps and slices are replaced in place
+			_, err := replaceMarkers(element.Interface(), markers)
+			return err
+		}
+		replaced, ok := markers[s]
+		if !ok {
+			return nil
+		}
+		if !reflect.TypeOf(replaced).AssignableTo(t) {
+			return fmt.Errorf("cannot decode %T into %s", replaced, t)
+		}
+		fn(reflect.ValueOf(replaced))
+		return nil
+	}
+	value := reflect.ValueOf(v)
+	switch value.Kind() {
+	case reflect.Map:
+		iter := value.MapRange()
+		for iter.Next() {
+			key := iter.Key()
+			err := set(iter.Value(), value.Type().Elem(), func(r reflect.Value) { value.SetMapIndex(key, r) })
+			if err != nil {
+				return nil, err
+			}
+		}
+	case reflect.Slice:
+		for j := 0; j < value.Len(); j++ {
+			err := set(value.Index(j), value.Type().Elem(), value.Index(j).Set)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	return v, nil
 }
 
-func resolveTags(node *yaml.Node) (*yaml.Node, error) {
+func (i *CustomTagProcessor) resolveTags(node *yaml.Node) (*yaml.Node, error) {
+	if resolved, ok := i.resolved[node]; ok {
+		return resolved, nil
+	}
+	resolved, err := i.resolveNode(node)
+	if err != nil {
+		return nil, err
+	}
+	i.resolved[node] = resolved
+	return resolved, nil
+}
+
+func (i *CustomTagProcessor) resolveNode(node *yaml.Node) (*yaml.Node, error) {
+	if fn, ok := i.resolvers[node.Tag]; ok {
+		return fn(node)
+	}
+	if isConditional(node) {
+		return nil, fmt.Errorf("line %d: %s is only supported for mapping values and sequence items", node.Line, node.Tag)
+	}
 	for tag, fn := range tagResolvers {
 		if node.Tag == tag {
 			return fn(node)
 		}
 	}
-	if node.Kind == yaml.SequenceNode || node.Kind == yaml.MappingNode {
+	if i.options.Strict || i.options.StrictBooleans {
+		err := i.checkStrict(node)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if node.Kind == yaml.AliasNode {
+		// resolve the anchored node, which might not be part of the document
+		// if it is a shared anchor
 		var err error
-		for i := range node.Content {
-			node.Content[i], err = resolveTags(node.Content[i])
+		node.Alias, err = i.resolveTags(node.Alias)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if node.Kind == yaml.SequenceNode || node.Kind == yaml.MappingNode {
+		content := make([]*yaml.Node, 0, len(node.Content))
+		for j, child := range node.Content {
+			isValue := node.Kind == yaml.SequenceNode || j%2 == 1
+			keep := true
+			for isValue && keep && isConditional(child) {
+				var err error
+				child, keep, err = i.resolveCondition(child)
+				if err != nil {
+					return nil, err
+				}
+			}
+			if !keep {
+				// drop the key of mapping values
+				if node.Kind == yaml.MappingNode {
+					content = content[:len(content)-1]
+				}
+				continue
+			}
+			child, err := i.resolveTags(child)
 			if err != nil {
 				return nil, err
 			}
+			// mapping keys are never interpolated
+			if i.options.Interpolate && isValue {
+				err = i.interpolate(child)
+				if err != nil {
+					return nil, err
+				}
+			}
+			content = append(content, child)
 		}
+		node.Content = content
 	}
 	return node, nil
 }
 
-func resolveEnv(node *yaml.Node) (*yaml.Node, error) {
+func (i *CustomTagProcessor) resolveEnv(node *yaml.Node) (*yaml.Node, error) {
 	if node.Kind != yaml.ScalarNode {
 		return nil, errors.New("!env on a non-scalar node")
 	}
-	value := os.Getenv(node.Value)
+	value := i.getenv(node.Value)
 	if value == "" {
 		return nil, fmt.Errorf("environment variable %v not set", node.Value)
 	}
@@ -62,8 +282,221 @@ func AddResolvers(tag string, fn func(*yaml.Node) (*yaml.Node, error)) {
 	tagResolversMutex.Unlock()
 }
 
+func newCustomTagProcessor(out interface{}, options YamlOptions) *CustomTagProcessor {
+	processor := &CustomTagProcessor{target: out, options: options, resolvers: fileResolvers(options.BaseDir)}
+	for _, tag := range options.CustomTags {
+		processor.resolvers[tag.Tag()] = tag.Resolve
+	}
+	for tag, fn := range processor.computationResolvers() {
+		processor.resolvers[tag] = fn
+	}
+	for tag, fn := range processor.cryptoResolvers() {
+		processor.resolvers[tag] = fn
+	}
+	processor.resolvers["!env"] = processor.resolveEnv
+	processor.resolvers["!var"] = processor.resolveVar
+	processor.resolvers["!ref"] = processor.resolveRef
+	return processor
+}
+
+// getenv returns the value of an environment variable, see getenv.
+func (i *CustomTagProcessor) getenv(name string) string {
+	if i.options.EnvFromVariables {
+		return i.options.Variables[name]
+	}
+	return getenv(i.options.Env, name)
+}
+
+func (i *CustomTagProcessor) resolveVar(node *yaml.Node) (*yaml.Node, error) {
+	if node.Kind != yaml.ScalarNode {
+		return nil, errors.New("!var on a non-scalar node")
+	}
+	value, ok := i.options.Variables[node.Value]
+	if !ok {
+		return nil, fmt.Errorf("variable %v not set", node.Value)
+	}
+	node.Value = value
+	return node, nil
+}
+
 func YamlUnmarshal(in []byte, out interface{}) error {
-	AddResolvers("!env", resolveEnv)
-	err := yaml.Unmarshal(in, &CustomTagProcessor{out})
+	return YamlUnmarshalWithOptions(in, out, YamlOptions{})
+}
+
+func YamlUnmarshalWithOptions(in []byte, out interface{}, options YamlOptions) error {
+	processor := newCustomTagProcessor(out, options)
+	if options.TemplateVars != nil {
+		name := options.Name
+		if name == "" {
+			name = "input"
+		}
+		var err error
+		in, processor.lines, err = renderTemplate(name, in, options.TemplateVars)
+		if err != nil {
+			return err
+		}
+	}
+	if options.SharedAnchors != nil {
+		var err error
+		in, processor.offset, err = addSharedAnchors(in, options.SharedAnchors)
+		if err != nil {
+			return err
+		}
+		processor.sharedAnchors = processor.offset > 0
+	}
+	err := yaml.Unmarshal(in, processor)
+	if err != nil && (processor.offset > 0 || processor.lines != nil) {
+		err = errors.New(mapErrorLines(err.Error(), processor.sourceLine))
+	}
 	return err
 }
+
+// sourceLine returns the line of the original YAML string for a line of the
+// decoded document, which might include shared anchors or be rendered from
+// a template.
+func (i *CustomTagProcessor) sourceLine(line int) int {
+	if line > i.offset {
+		line -= i.offset
+	}
+	if line > 0 && line <= len(i.lines) {
+		line = i.lines[line-1]
+	}
+	return line
+}
+
+// sharedAnchorsKey is the key of the map entry, which is added to the
+// beginning of a YAML string to define anchors of previous YAML strings.
+const sharedAnchorsKey = "__shared_anchors__"
+
+var unknownAnchorRegex = regexp.MustCompile(`unknown anchor '(.+)' referenced`)
+var errorLineRegex = regexp.MustCompile(`line (\d+)`)
+
+// addSharedAnchors adds the definitions of all anchors referenced in, but
+// not defined by a YAML string to its beginning. The anchors are reported by
+// the parser, so aliases in quoted strings and comments are ignored. The
+// definitions are added as first key of the root mapping, which therefore
+// must be a block mapping. It returns the number of added lines.
+func addSharedAnchors(in []byte, anchors map[string]*yaml.Node) ([]byte, int, error) {
+	shared := &yaml.Node{Kind: yaml.SequenceNode, Style: yaml.FlowStyle}
+	result := in
+	added := 0
+	for {
+		var node yaml.Node
+		err := yaml.Unmarshal(result, &node)
+		if err == nil {
+			return result, added, nil
+		}
+		m := unknownAnchorRegex.FindStringSubmatch(err.Error())
+		if m == nil {
+			if added > 0 && !isBlockMappingRoot(in) {
+				return nil, 0, fmt.Errorf("shared anchors can only be referenced in YAML strings with a block mapping at the root")
+			}
+			// the error is reported by the caller
+			return result, added, nil
+		}
+		if anchors[m[1]] == nil {
+			return result, added, nil
+		}
+		if !isBlockMappingRoot(in) {
+			return nil, 0, fmt.Errorf("shared anchor '%s' can only be referenced in YAML strings with a block mapping at the root", m[1])
+		}
+		anchor := flowCopy(anchors[m[1]])
+		anchor.Anchor = m[1]
+		shared.Content = append(shared.Content, anchor)
+
+		definitions, err := yaml.Marshal(&yaml.Node{Kind: yaml.MappingNode, Content: []*yaml.Node{
+			{Kind: yaml.ScalarNode, Value: sharedAnchorsKey},
+			shared,
+		}})
+		if err != nil {
+			return nil, 0, err
+		}
+		lines := strings.SplitAfter(string(in), "\n")
+		position := documentStart(lines)
+		result = []byte(strings.Join(lines[:position], "") + string(definitions) + strings.Join(lines[position:], ""))
+		added = strings.Count(string(definitions), "\n")
+	}
+}
+
+// documentStart returns the index of the first line after directives and
+// document start markers.
+func documentStart(lines []string) int {
+	for j, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "---" {
+			return j + 1
+		}
+		if trimmed != "" && !strings.HasPrefix(trimmed, "#") && !strings.HasPrefix(trimmed, "%") {
+			break
+		}
+	}
+	return 0
+}
+
+// isBlockMappingRoot returns true if the first content line of a YAML string
+// starts a block mapping at the first column.
+func isBlockMappingRoot(in []byte) bool {
+	lines := strings.SplitAfter(string(in), "\n")
+	for _, line := range lines[documentStart(lines):] {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		if line[0] == ' ' || line[0] == '\t' || trimmed[0] == '{' || trimmed[0] == '[' || trimmed == "-" || strings.HasPrefix(trimmed, "- ") || strings.HasPrefix(trimmed, "---") {
+			return false
+		}
+		return strings.Contains(trimmed, ":")
+	}
+	return false
+}
+
+// flowCopy returns a copy of a resolved node in flow style without anchors,
+// aliases and custom tags.
+func flowCopy(node *yaml.Node) *yaml.Node {
+	if node.Kind == yaml.AliasNode {
+		return flowCopy(node.Alias)
+	}
+	c := &yaml.Node{Kind: node.Kind, Tag: node.Tag, Value: node.Value, Style: node.Style}
+	if strings.HasPrefix(c.Tag, "!") && !strings.HasPrefix(c.Tag, "!!") {
+		c.Tag = ""
+		if c.Kind == yaml.ScalarNode {
+			c.Tag = "!!str"
+		}
+	}
+	switch c.Kind {
+	case yaml.MappingNode, yaml.SequenceNode:
+		c.Style = yaml.FlowStyle
+		for _, child := range node.Content {
+			c.Content = append(c.Content, flowCopy(child))
+		}
+	case yaml.ScalarNode:
+		if c.Style&(yaml.LiteralStyle|yaml.FoldedStyle) != 0 {
+			c.Style = yaml.DoubleQuotedStyle
+		}
+	}
+	return c
+}
+
+func markResolved(node *yaml.Node, resolved map[*yaml.Node]*yaml.Node) {
+	resolved[node] = node
+	for _, child := range node.Content {
+		markResolved(child, resolved)
+	}
+}
+
+func collectAnchors(node *yaml.Node, anchors map[string]*yaml.Node) {
+	if node.Anchor != "" {
+		anchors[node.Anchor] = node
+	}
+	for _, child := range node.Content {
+		collectAnchors(child, anchors)
+	}
+}
+
+// mapErrorLines corrects line numbers in YAML errors using fn.
+func mapErrorLines(msg string, fn func(int) int) string {
+	return errorLineRegex.ReplaceAllStringFunc(msg, func(m string) string {
+		line, _ := strconv.Atoi(m[5:])
+		return "line " + strconv.Itoa(fn(line))
+	})
+}
@@ -0,0 +1,275 @@
+package provider
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestYamlUnmarshalAliases(t *testing.T) {
+	t.Setenv("YAML_TEST_ALIAS", "value1")
+
+	cases := []struct {
+		input  string
+		result map[string]interface{}
+	}{
+		// tags in anchored maps are resolved for aliases
+		{
+			input: `
+base: &base
+  elem1: !env YAML_TEST_ALIAS
+other: *base
+`,
+			result: map[string]interface{}{
+				"base":  map[string]interface{}{"elem1": "value1"},
+				"other": map[string]interface{}{"elem1": "value1"},
+			},
+		},
+		// anchored scalars with tags are resolved once
+		{
+			input: `
+elem1: &elem !env YAML_TEST_ALIAS
+elem2: *elem
+`,
+			result: map[string]interface{}{
+				"elem1": "value1",
+				"elem2": "value1",
+			},
+		},
+		// merge keys
+		{
+			input: `
+base: &base
+  elem1: !env YAML_TEST_ALIAS
+  elem2: value2
+other:
+  <<: *base
+  elem2: value3
+`,
+			result: map[string]interface{}{
+				"base":  map[string]interface{}{"elem1": "value1", "elem2": "value2"},
+				"other": map[string]interface{}{"elem1": "value1", "elem2": "value3"},
+			},
+		},
+	}
+
+	for _, c := range cases {
+		var data map[string]interface{}
+		err := YamlUnmarshal([]byte(c.input), &data)
+		if err != nil {
+			t.Fatalf("Error reading YAML string: %s", err)
+		}
+		if !reflect.DeepEqual(data, c.result) {
+			t.Fatalf("Error matching data and result: %#v vs %#v", data, c.result)
+		}
+	}
+}
+
+func TestYamlUnmarshalSharedAnchors(t *testing.T) {
+	t.Setenv("YAML_TEST_ALIAS", "value1")
+
+	inputs := []string{`
+base: &base
+  elem1: !env YAML_TEST_ALIAS
+  text: |
+    line1
+    line2
+list: &list [a1, a2]
+`, `
+# comment
+---
+other:
+  <<: *base
+  elem2: value2
+list: *list
+`}
+	result := map[interface{}]interface{}{
+		"base":  map[string]interface{}{"elem1": "value1", "text": "line1\nline2\n"},
+		"other": map[string]interface{}{"elem1": "value1", "elem2": "value2", "text": "line1\nline2\n"},
+		"list":  []interface{}{"a1", "a2"},
+	}
+
+	merged := map[interface{}]interface{}{}
+	anchors := map[string]*yaml.Node{}
+	for _, input := range inputs {
+		var data map[interface{}]interface{}
+		err := YamlUnmarshalWithOptions([]byte(input), &data, YamlOptions{SharedAnchors: anchors})
+		if err != nil {
+			t.Fatalf("Error reading YAML string: %s", err)
+		}
+		MergeMaps(reflect.ValueOf(merged), reflect.ValueOf(data), true)
+	}
+	if !reflect.DeepEqual(merged, result) {
+		t.Fatalf("Error matching merged and result: %#v vs %#v", merged, result)
+	}
+
+	// anchors are not shared by default
+	err := YamlUnmarshal([]byte(inputs[1]), &map[string]interface{}{})
+	if err == nil {
+		t.Fatalf("Expected error for unknown anchor")
+	}
+
+	// line numbers of errors refer to the original input
+	err = YamlUnmarshalWithOptions([]byte("elem1: *base\nelem2: [\n"), &map[string]interface{}{}, YamlOptions{SharedAnchors: anchors})
+	if err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Fatalf("Expected error in line 2, got: %v", err)
+	}
+}
+
+func TestAddSharedAnchors(t *testing.T) {
+	anchors := map[string]*yaml.Node{
+		"base": {Kind: yaml.ScalarNode, Tag: "!!str", Value: "value1"},
+	}
+
+	cases := []struct {
+		input  string
+		result string
+		err    string
+	}{
+		// aliases in strings and comments are ignored
+		{
+			input:  "a: \"*base\" # *base\nb: '*base'\n",
+			result: "a: \"*base\" # *base\nb: '*base'\n",
+		},
+		{
+			input:  "{a: \"*base\"}\n",
+			result: "{a: \"*base\"}\n",
+		},
+		// anchors defined in the input are not shared
+		{
+			input:  "a: &base 1\nb: *base\n",
+			result: "a: &base 1\nb: *base\n",
+		},
+		{
+			input:  "# comment\n---\na: *base\n",
+			result: "# comment\n---\n__shared_anchors__: [&base value1]\na: *base\n",
+		},
+		// only block mappings at the root can reference shared anchors
+		{
+			input: "{a: *base}\n",
+			err:   "shared anchor 'base' can only be referenced in YAML strings with a block mapping at the root",
+		},
+		{
+			input: "  a: *base\n",
+			err:   "shared anchor 'base' can only be referenced in YAML strings with a block mapping at the root",
+		},
+		{
+			input: "- *base\n",
+			err:   "shared anchor 'base' can only be referenced in YAML strings with a block mapping at the root",
+		},
+	}
+
+	for _, c := range cases {
+		result, _, err := addSharedAnchors([]byte(c.input), anchors)
+		if c.err != "" {
+			if err == nil || !strings.Contains(err.Error(), c.err) {
+				t.Fatalf("Error matching error: %v vs %s", err, c.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("Error adding shared anchors: %s", err)
+		}
+		if string(result) != c.result {
+			t.Fatalf("Error matching result: %q vs %q", result, c.result)
+		}
+	}
+}
+
+// Values added by merge keys are part of the decoded maps and therefore used
+// when matching list items.
+func TestMergeMapsMergeKeys(t *testing.T) {
+	inputs := []string{`
+defaults: &defaults
+  elem1: value1
+  elem2: value2
+list:
+  - <<: *defaults
+    name: a1
+`, `
+defaults: &defaults
+  elem1: value3
+list:
+  - name: a1
+    child:
+      elem3: value4
+  - <<: *defaults
+    name: a2
+`}
+	result := map[interface{}]interface{}{
+		"defaults": map[string]interface{}{"elem1": "value3", "elem2": "value2"},
+		"list": []interface{}{
+			map[string]interface{}{"name": "a1", "elem1": "value1", "elem2": "value2", "child": map[string]interface{}{"elem3": "value4"}},
+			map[string]interface{}{"name": "a2", "elem1": "value3"},
+		},
+	}
+
+	merged := map[interface{}]interface{}{}
+	for _, input := range inputs {
+		var data map[interface{}]interface{}
+		err := YamlUnmarshal([]byte(input), &data)
+		if err != nil {
+			t.Fatalf("Error reading YAML string: %s", err)
+		}
+		MergeMaps(reflect.ValueOf(merged), reflect.ValueOf(data), true)
+	}
+	if !reflect.DeepEqual(merged, result) {
+		t.Fatalf("Error matching merged and result: %#v vs %#v", merged, result)
+	}
+}
+
+func TestYamlUnmarshalVariables(t *testing.T) {
+	t.Setenv("YAML_TEST_VAR", "process")
+
+	cases := []struct {
+		input   string
+		options YamlOptions
+		result  map[string]interface{}
+		err     string
+	}{
+		{
+			input:   "var: !var NAME\nenv: !env YAML_TEST_VAR\n",
+			options: YamlOptions{Variables: map[string]string{"NAME": "value1"}},
+			result:  map[string]interface{}{"var": "value1", "env": "process"},
+		},
+		{
+			input:   "env: !env YAML_TEST_VAR\ninterpolated: ${env.YAML_TEST_VAR}\n",
+			options: YamlOptions{Variables: map[string]string{"YAML_TEST_VAR": "variable"}, EnvFromVariables: true, Interpolate: true},
+			result:  map[string]interface{}{"env": "variable", "interpolated": "variable"},
+		},
+		{
+			input:   "list:\n  - !if {condition: env.STAGE == \"prod\", value: prod}\n  - !if {condition: env.YAML_TEST_VAR, value: process}\n",
+			options: YamlOptions{Variables: map[string]string{"STAGE": "prod"}, EnvFromVariables: true},
+			result:  map[string]interface{}{"list": []interface{}{"prod"}},
+		},
+		{
+			input:   "var: !var MISSING\n",
+			options: YamlOptions{Variables: map[string]string{}},
+			err:     "variable MISSING not set",
+		},
+		{
+			input:   "env: !env YAML_TEST_VAR\n",
+			options: YamlOptions{EnvFromVariables: true},
+			err:     "environment variable YAML_TEST_VAR not set",
+		},
+	}
+
+	for _, c := range cases {
+		var data map[string]interface{}
+		err := YamlUnmarshalWithOptions([]byte(c.input), &data, c.options)
+		if c.err != "" {
+			if err == nil || !strings.Contains(err.Error(), c.err) {
+				t.Fatalf("Error matching error: %v vs %s", err, c.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("Error reading YAML string: %s", err)
+		}
+		if !reflect.DeepEqual(data, c.result) {
+			t.Fatalf("Error matching data and result: %#v vs %#v", data, c.result)
+		}
+	}
+}